@@ -0,0 +1,134 @@
+// Package alignment holds multiple sequence alignments and reads and writes
+// them in the CLUSTAL, Stockholm, aligned FASTA and PHYLIP formats, along
+// with a BLAST style view of pairwise alignments.
+package alignment
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/internal/lineio"
+)
+
+// blockWidth is the number of columns per block of the interleaved formats.
+const blockWidth = 60
+
+// Alignment is a set of named sequences of equal length, gaps (bio.X)
+// included.
+type Alignment struct {
+	Names []string
+	Seqs  []*bio.Sequence
+	// Starts are the positions of the first aligned residues in the
+	// unaligned sequences, nil if all zero as for global alignments.
+	Starts []int
+}
+
+// New returns the alignment of seqs, an error if their lengths differ.
+// Names may be nil, naming the sequences seq1, seq2 and so on.
+func New(names []string, seqs []*bio.Sequence) (*Alignment, error) {
+	if names == nil {
+		for i := range seqs {
+			names = append(names, fmt.Sprintf("seq%d", i+1))
+		}
+	}
+	if len(names) != len(seqs) {
+		return nil, fmt.Errorf("alignment: %d names for %d sequences", len(names), len(seqs))
+	}
+	for i, seq := range seqs {
+		if len(seq.Bases) != len(seqs[0].Bases) {
+			return nil, fmt.Errorf("alignment: %s has %d columns, %s %d",
+				names[i], len(seq.Bases), names[0], len(seqs[0].Bases))
+		}
+	}
+	return &Alignment{Names: names, Seqs: seqs}, nil
+}
+
+// Columns returns the number of columns.
+func (a *Alignment) Columns() int {
+	if len(a.Seqs) == 0 {
+		return 0
+	}
+	return len(a.Seqs[0].Bases)
+}
+
+// start returns the position of the first aligned residue of sequence i.
+func (a *Alignment) start(i int) int {
+	if a.Starts == nil {
+		return 0
+	}
+	return a.Starts[i]
+}
+
+// nameWidth returns the width of the name column: the longest name and
+// at least min, plus a space.
+func (a *Alignment) nameWidth(min int) int {
+	width := min
+	for _, name := range a.Names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	return width + 1
+}
+
+// letters returns the letters of the columns from start to end of
+// sequence i.
+func (a *Alignment) letters(i, start, end int) string {
+	seq := &bio.Sequence{Bases: a.Seqs[i].Bases[start:end]}
+	return seq.String()
+}
+
+// builder collects the rows of an alignment read in blocks.
+type builder struct {
+	names []string
+	rows  []*strings.Builder
+	index map[string]int
+}
+
+func newBuilder() *builder {
+	return &builder{index: map[string]int{}}
+}
+
+// add appends letters to the row of name, adding the row if new.
+func (b *builder) add(name, letters string) {
+	k, ok := b.index[name]
+	if !ok {
+		k = len(b.names)
+		b.index[name] = k
+		b.names = append(b.names, name)
+		b.rows = append(b.rows, &strings.Builder{})
+	}
+	b.rows[k].WriteString(letters)
+}
+
+// alignment parses the rows with the alphabet a.
+func (b *builder) alignment(a *bio.Alphabet) (*Alignment, error) {
+	if len(b.names) == 0 {
+		return nil, fmt.Errorf("alignment: no sequences")
+	}
+	seqs := make([]*bio.Sequence, len(b.rows))
+	for k, row := range b.rows {
+		seq, err := bio.ParseSequence(row.String(), a)
+		if err != nil {
+			return nil, fmt.Errorf("alignment: %s: %v", b.names[k], err)
+		}
+		seqs[k] = seq
+	}
+	return New(b.names, seqs)
+}
+
+// lineReader reads lines, with errors at the current line.
+type lineReader struct {
+	*lineio.Reader
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{lineio.NewReader(r)}
+}
+
+// errorf returns an error at the current line.
+func (lr *lineReader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("alignment: line %d: %s", lr.Line, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,176 @@
+package alignment
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+func sample(t *testing.T) *Alignment {
+	seqs, err := bio.ParseSequences([]string{
+		strings.Repeat("ACGT-ACGTA", 7),
+		strings.Repeat("ACGTTACG-A", 7),
+		strings.Repeat("TCGT-ACGCA", 7),
+	}, bio.DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := New([]string{"first", "second", "a_longer_name"}, seqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func equal(a, b *Alignment) bool {
+	if len(a.Seqs) != len(b.Seqs) {
+		return false
+	}
+	for i := range a.Seqs {
+		if a.Names[i] != b.Names[i] || a.Seqs[i].String() != b.Seqs[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+// TestRoundTrips writes and reads back the sample in every format.
+func TestRoundTrips(t *testing.T) {
+	a := sample(t)
+	formats := []struct {
+		name  string
+		write func(io.Writer, *Alignment) error
+		read  func(io.Reader, *bio.Alphabet) (*Alignment, error)
+	}{
+		{"CLUSTAL", WriteClustal, ReadClustal},
+		{"Stockholm", WriteStockholm, ReadStockholm},
+		{"FASTA", WriteFASTA, ReadFASTA},
+		{"PHYLIP", WritePHYLIP, ReadPHYLIP},
+	}
+	for _, f := range formats {
+		var buf bytes.Buffer
+		if err := f.write(&buf, a); err != nil {
+			t.Fatal(err)
+		}
+		b, err := f.read(&buf, bio.DNA)
+		if err != nil {
+			t.Fatalf("%v: %v", f.name, err)
+		}
+		if !equal(a, b) {
+			t.Errorf("%v: read back %v %v", f.name, b.Names, b.Seqs)
+		}
+	}
+}
+
+func TestClustal(t *testing.T) {
+	var buf bytes.Buffer
+	WriteClustal(&buf, sample(t))
+	lines := strings.Split(buf.String(), "\n")
+	if lines[0] != "CLUSTAL W multiple sequence alignment" ||
+		lines[2] != "first           "+strings.Repeat("ACGT-ACGTA", 6)+" 54" ||
+		lines[5] != "                "+strings.Repeat(" *** *** *", 6) ||
+		lines[8] != "second          ACGTTACG-A 63" {
+		t.Errorf("wrote\n%v", buf.String())
+	}
+
+	seqs, _ := bio.ParseSequences([]string{"MKVL", "MRIL", "MKAF"}, bio.Protein)
+	a, _ := New(nil, seqs)
+	if marks := string([]byte{a.conservation(0), a.conservation(1), a.conservation(2),
+		a.conservation(3)}); marks != "*: :" {
+		t.Errorf("protein conservation %q", marks)
+	}
+}
+
+func TestInterleaved(t *testing.T) {
+	stockholm := `# STOCKHOLM 1.0
+#=GF ID example
+s1 ACGT
+s2 AC.T
+
+#=GC SS_cons <<>>
+s1 AA
+s2 -A
+//
+`
+	a, err := ReadStockholm(strings.NewReader(stockholm), bio.DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Seqs[0].String() != "ACGTAA" || a.Seqs[1].String() != "AC-T-A" {
+		t.Errorf("Stockholm %v", a.Seqs)
+	}
+
+	phylip := "2 10\ns1 ACGTA\ns2 AC-TA\n\nCGT AC\nCG- AC\n"
+	a, err = ReadPHYLIP(strings.NewReader(phylip), bio.DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Names[1] != "s2" || a.Seqs[0].String() != "ACGTACGTAC" || a.Seqs[1].String() != "AC-TACG-AC" {
+		t.Errorf("PHYLIP %v %v", a.Names, a.Seqs)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	cases := []struct {
+		read func(io.Reader, *bio.Alphabet) (*Alignment, error)
+		in   string
+	}{
+		{ReadClustal, "not clustal\n"},
+		{ReadClustal, "CLUSTAL\n\ns1 ACGT\ns2 ACG\n"},
+		{ReadStockholm, "# STOCKHOLM 1.0\ns1 ACGT\n"},
+		{ReadPHYLIP, "2 4\ns1 ACGT\n"},
+		{ReadPHYLIP, "2 4\ns1 ACGT\ns2 ACGA\nT\nT\n"},
+		{ReadPHYLIP, "1 4\ns1 ACGE\n"},
+		{ReadFASTA, ">s1\nACGT\n>s2\nAC\n"},
+	}
+	for _, c := range cases {
+		if _, err := c.read(strings.NewReader(c.in), bio.DNA); err == nil {
+			t.Errorf("%q: expected an error", c.in)
+		}
+	}
+}
+
+func TestWritePairwise(t *testing.T) {
+	seqs := parse(t, "ACGTAC-GT", "ACGAACTGT")
+	a, _ := New([]string{"query", "sbjct"}, seqs)
+	a.Starts = []int{0, 2}
+	var buf bytes.Buffer
+	if err := WritePairwise(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	want := `Identities = 7/9 (78%), Gaps = 1/9 (11%)
+
+query 1  ACGTAC-GT  8
+         ||| || ||
+sbjct 3  ACGAACTGT  11
+
+`
+	if buf.String() != want {
+		t.Errorf("wrote\n%v", buf.String())
+	}
+
+	// every column is shown, in blocks
+	long := parse(t, strings.Repeat("A", 130), strings.Repeat("A", 130))
+	a, _ = New(nil, long)
+	buf.Reset()
+	WritePairwise(&buf, a)
+	if strings.Count(buf.String(), "A") != 260 || !strings.Contains(buf.String(), "121 AAAAAAAAAA  130") {
+		t.Errorf("wrote\n%v", buf.String())
+	}
+	if err := WritePairwise(&buf, sample(t)); err == nil {
+		t.Error("expected an error for three sequences")
+	}
+}
+
+// parse reads DNA sequences, failing the test on other letters.
+func parse(t *testing.T, strs ...string) []*bio.Sequence {
+	t.Helper()
+	seqs, err := bio.ParseSequences(strs, bio.DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seqs
+}
@@ -0,0 +1,117 @@
+package alignment
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// The CLUSTAL format interleaves blocks of 60 columns, every line holding a
+// name, the letters of the block and the count of residues so far. A line
+// below each block marks conserved columns:
+//   '*' all residues identical
+//   ':' residues of a strong group (proteins only)
+//   '.' residues of a weak group (proteins only)
+
+// conservation groups of ClustalW
+var (
+	strongGroups = []string{"STA", "NEQK", "NHQK", "NDEQ", "QHRK", "MILV", "MILF", "HY", "FYW"}
+	weakGroups   = []string{"CSA", "ATV", "SAG", "STNK", "STPA", "SGND", "SNDEQK", "NDEQHK",
+		"NEQHRK", "FVLIM", "HFY"}
+)
+
+// WriteClustal writes a in the CLUSTAL format.
+func WriteClustal(w io.Writer, a *Alignment) error {
+	var b bytes.Buffer
+	b.WriteString("CLUSTAL W multiple sequence alignment\n\n")
+	width := a.nameWidth(15)
+	counts := make([]int, len(a.Seqs))
+	for i := range counts {
+		counts[i] = a.start(i)
+	}
+	for start := 0; start < a.Columns(); start += blockWidth {
+		end := start + blockWidth
+		if end > a.Columns() {
+			end = a.Columns()
+		}
+		for i, seq := range a.Seqs {
+			for _, base := range seq.Bases[start:end] {
+				if base != bio.X {
+					counts[i]++
+				}
+			}
+			fmt.Fprintf(&b, "%-*s%s %d\n", width, a.Names[i], a.letters(i, start, end), counts[i])
+		}
+		b.WriteString(strings.Repeat(" ", width))
+		for col := start; col < end; col++ {
+			b.WriteByte(a.conservation(col))
+		}
+		b.WriteString("\n\n")
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// conservation returns the mark of a column.
+func (a *Alignment) conservation(col int) byte {
+	column := make([]byte, len(a.Seqs))
+	for i, seq := range a.Seqs {
+		if seq.Bases[col] == bio.X {
+			return ' '
+		}
+		column[i] = seq.Bases[col].Letter()
+	}
+	if strings.Count(string(column), string(column[0])) == len(column) {
+		return '*'
+	}
+	if a.Seqs[0].Alphabet != bio.Protein {
+		return ' '
+	}
+	for _, group := range strongGroups {
+		if strings.Trim(string(column), group) == "" {
+			return ':'
+		}
+	}
+	for _, group := range weakGroups {
+		if strings.Trim(string(column), group) == "" {
+			return '.'
+		}
+	}
+	return ' '
+}
+
+// ReadClustal reads an alignment of sequences of the alphabet al in the
+// CLUSTAL format. The header line may name any program, as MUSCLE does.
+func ReadClustal(r io.Reader, al *bio.Alphabet) (*Alignment, error) {
+	lr := newLineReader(r)
+	header, err := lr.Next()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !strings.Contains(header, "CLUSTAL") && !strings.Contains(header, "MUSCLE") {
+		return nil, lr.errorf("expected a CLUSTAL header")
+	}
+	b := newBuilder()
+	for {
+		line, err := lr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// blank and conservation lines start with spaces
+		if strings.TrimSpace(line) == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, lr.errorf("expected a name, letters and a count")
+		}
+		b.add(fields[0], fields[1])
+	}
+	return b.alignment(al)
+}
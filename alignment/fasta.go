@@ -0,0 +1,36 @@
+package alignment
+
+import (
+	"io"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/fasta"
+)
+
+// WriteFASTA writes a as aligned FASTA: a record per sequence, gaps
+// included.
+func WriteFASTA(w io.Writer, a *Alignment) error {
+	fw := fasta.NewWriter(w)
+	for i, seq := range a.Seqs {
+		if err := fw.Write(&fasta.Record{ID: a.Names[i], Seq: seq}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFASTA reads an alignment of sequences of the alphabet al from aligned
+// FASTA, an error if the records differ in length. Names are the record
+// IDs.
+func ReadFASTA(r io.Reader, al *bio.Alphabet) (*Alignment, error) {
+	recs, err := fasta.NewReader(r, al).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(recs))
+	seqs := make([]*bio.Sequence, len(recs))
+	for i, rec := range recs {
+		names[i], seqs[i] = rec.ID, rec.Seq
+	}
+	return New(names, seqs)
+}
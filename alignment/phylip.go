@@ -0,0 +1,84 @@
+package alignment
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// The PHYLIP format starts with the number of sequences and of columns,
+// followed by a line per sequence holding its name and letters. In the
+// interleaved form, further blocks hold the following letters of every
+// sequence in the same order, without names. Names are relaxed: any length
+// without spaces, separated from the letters by whitespace, rather than
+// padded to 10 characters.
+
+// WritePHYLIP writes a in the relaxed sequential PHYLIP format.
+func WritePHYLIP(w io.Writer, a *Alignment) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d %d\n", len(a.Seqs), a.Columns())
+	width := a.nameWidth(10)
+	for i := range a.Seqs {
+		fmt.Fprintf(&b, "%-*s%s\n", width, a.Names[i], a.letters(i, 0, a.Columns()))
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// ReadPHYLIP reads an alignment of sequences of the alphabet al in the
+// relaxed PHYLIP format, sequential or interleaved. Letters may be split by
+// spaces.
+func ReadPHYLIP(r io.Reader, al *bio.Alphabet) (*Alignment, error) {
+	lr := newLineReader(r)
+	header, err := lr.Next()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 2 {
+		return nil, lr.errorf("expected the numbers of sequences and columns")
+	}
+	n, err1 := strconv.Atoi(fields[0])
+	columns, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || n <= 0 || columns < 0 {
+		return nil, lr.errorf("expected the numbers of sequences and columns")
+	}
+	b := newBuilder()
+	for k := 0; ; k++ {
+		line, err := lr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			k--
+			continue
+		}
+		if k < n {
+			if _, ok := b.index[fields[0]]; ok {
+				return nil, lr.errorf("duplicate name %s", fields[0])
+			}
+			b.add(fields[0], strings.Join(fields[1:], ""))
+		} else {
+			b.add(b.names[k%n], strings.Join(fields, ""))
+		}
+	}
+	if len(b.names) != n {
+		return nil, lr.errorf("%d sequences, expected %d", len(b.names), n)
+	}
+	a, err := b.alignment(al)
+	if err != nil {
+		return nil, err
+	}
+	if a.Columns() != columns {
+		return nil, fmt.Errorf("alignment: %d columns, expected %d", a.Columns(), columns)
+	}
+	return a, nil
+}
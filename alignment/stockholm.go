@@ -0,0 +1,64 @@
+package alignment
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// The Stockholm format of Pfam and Rfam starts with "# STOCKHOLM 1.0" and
+// ends with "//". Between them every line holds a name and letters, the
+// rows of a sequence may be split in blocks, and lines starting with '#'
+// are comments or markup (#=GF, #=GS, #=GR, #=GC), skipped on reading.
+
+// WriteStockholm writes a in the Stockholm format, in a single block.
+func WriteStockholm(w io.Writer, a *Alignment) error {
+	var b bytes.Buffer
+	b.WriteString("# STOCKHOLM 1.0\n\n")
+	width := a.nameWidth(0)
+	for i := range a.Seqs {
+		fmt.Fprintf(&b, "%-*s%s\n", width, a.Names[i], a.letters(i, 0, a.Columns()))
+	}
+	b.WriteString("//\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// ReadStockholm reads the first alignment of sequences of the alphabet al
+// in the Stockholm format.
+func ReadStockholm(r io.Reader, al *bio.Alphabet) (*Alignment, error) {
+	lr := newLineReader(r)
+	header, err := lr.Next()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !strings.HasPrefix(header, "# STOCKHOLM") {
+		return nil, lr.errorf("expected a Stockholm header")
+	}
+	b := newBuilder()
+	for {
+		line, err := lr.Next()
+		if err == io.EOF {
+			return nil, lr.errorf("missing //")
+		}
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "//" {
+			break
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, lr.errorf("expected a name and letters")
+		}
+		b.add(fields[0], fields[1])
+	}
+	return b.alignment(al)
+}
@@ -0,0 +1,84 @@
+package alignment
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// WritePairwise writes an alignment of two sequences as BLAST does, in
+// blocks of 60 columns with the positions of the first and last residues of
+// every line and a match line marking identical residues with '|':
+//
+//	Identities = 7/9 (78%), Gaps = 1/9 (11%)
+//
+//	query 1  ACGTAC-GT  8
+//	         ||| || ||
+//	sbjct 3  ACGAACTGT  11
+func WritePairwise(w io.Writer, a *Alignment) error {
+	if len(a.Seqs) != 2 {
+		return fmt.Errorf("alignment: %d sequences in a pairwise alignment", len(a.Seqs))
+	}
+	x, y := a.Seqs[0].Bases, a.Seqs[1].Bases
+	identities, gaps := 0, 0
+	for col := range x {
+		switch {
+		case x[col] == bio.X || y[col] == bio.X:
+			gaps++
+		case x[col] == y[col]:
+			identities++
+		}
+	}
+	var b bytes.Buffer
+	n := a.Columns()
+	fmt.Fprintf(&b, "Identities = %d/%d (%d%%), Gaps = %d/%d (%d%%)\n\n",
+		identities, n, percent(identities, n), gaps, n, percent(gaps, n))
+
+	nameWidth := a.nameWidth(0)
+	// positions are 1-based, the first one shown is start+1
+	pos := []int{a.start(0), a.start(1)}
+	posWidth := len(fmt.Sprint(pos[0] + n))
+	if w := len(fmt.Sprint(pos[1] + n)); w > posWidth {
+		posWidth = w
+	}
+	for start := 0; start < n; start += blockWidth {
+		end := start + blockWidth
+		if end > n {
+			end = n
+		}
+		for i := range a.Seqs {
+			first := pos[i] + 1
+			for _, base := range a.Seqs[i].Bases[start:end] {
+				if base != bio.X {
+					pos[i]++
+				}
+			}
+			fmt.Fprintf(&b, "%-*s%-*d %s  %d\n", nameWidth, a.Names[i], posWidth, first,
+				a.letters(i, start, end), pos[i])
+			if i == 0 {
+				fmt.Fprintf(&b, "%*s", nameWidth+posWidth+1, "")
+				for col := start; col < end; col++ {
+					if x[col] != bio.X && x[col] == y[col] {
+						b.WriteByte('|')
+					} else {
+						b.WriteByte(' ')
+					}
+				}
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// percent returns 100*k/n rounded, 0 if n is.
+func percent(k, n int) int {
+	if n == 0 {
+		return 0
+	}
+	return (200*k + n) / (2 * n)
+}
@@ -19,3 +19,108 @@ func TestConversion(t *testing.T) {
 	}
 	math.Abs(2)
 }
+
+func TestSPScore(t *testing.T) {
+	seqs, err := ParseSequences([]string{"AC--GT", "ACTTGT", "A--TGT"}, DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1-2: 3+3-1.5-1.5+3+3, 1-3: 3-1.5+0-1.5+3+3, 2-3: 3-1.5-1.5+3+3+3
+	if s := DefaultScoring.Float(SPScore(seqs, DefaultScoring)); s != 24 {
+		t.Errorf("score %v, expected 24", s)
+	}
+	if PairScore(A, A) != 6 || PairScore(A, C) != -4 || PairScore(A, X) != -3 || PairScore(X, X) != 0 {
+		t.Error("PairScore differs from the default scores")
+	}
+	affine := DefaultScoring
+	affine.Gaps = GapCosts{Open: -10, Extend: -1}
+	// 1-2: 6+6-11-1+6+6, 1-3: 6-11+0-1+6+6, 2-3: 6-11-1+6+6+6
+	// the gap of 1 in column 4 continues its gap in column 3 (quasi-natural)
+	if s := SPScore(seqs, affine); s != 30 {
+		t.Errorf("affine score %v, expected 30", s)
+	}
+	unit := ScoringScheme{Substitution: MatchMismatch(1, 0), Gaps: GapCosts{Extend: -1}}
+	if s := unit.Float(SPScore(seqs, unit)); s != 5 {
+		t.Errorf("unit score %v, expected 5", s)
+	}
+}
+
+func TestAlphabets(t *testing.T) {
+	seq, err := ParseSequence("mkV-*", Protein)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq.String() != "MKV-*" || seq.Alphabet != Protein || !Protein.Contains(seq.Bases[0]) {
+		t.Errorf("protein %v", seq)
+	}
+	if seq, err := ParseSequence("ACGU", RNA); err != nil || seq.Bases[3] != U {
+		t.Errorf("RNA %v %v", seq, err)
+	}
+	for _, c := range []struct {
+		s string
+		a *Alphabet
+	}{
+		{"ACGU", DNA},
+		{"ACGN", DNA},
+		{"ACGT", RNA},
+		{"ACGTE", IUPAC},
+		{"MKV#", Protein},
+	} {
+		if _, err := ParseSequence(c.s, c.a); err == nil {
+			t.Errorf("%v accepted as %v", c.s, c.a.Name)
+		}
+	}
+	if DNA.Contains(Met) || !IUPAC.Contains(AnyBase) || IUPAC.Contains(Asn) {
+		t.Error("Contains")
+	}
+	// AToSeq reads E, not a nucleotide, as a gap
+	if s := AToSeq("ACME").String(); s != "ACM-" || AToBase("E") != X {
+		t.Errorf("AToSeq read ACME as %v", s)
+	}
+}
+
+func TestAmbiguity(t *testing.T) {
+	seq, err := ParseSequence("ACGTNRY-", IUPAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq.Bases[4] != AnyBase || seq.Bases[5] != Purine || seq.Bases[7] != X || seq.String() != "ACGTNRY-" {
+		t.Fatalf("parsed %v", seq.Bases)
+	}
+	best := DefaultScoring
+	best.Ambiguity = BestMatch
+	nuc := NUC44.Scheme(GapCosts{})
+	cases := []struct {
+		a, b                Base
+		expected, best, nuc int
+	}{
+		{AnyBase, A, -2, 6, -2}, // (6-3*4)/4 rounded away from zero
+		{Purine, A, 1, 6, 1},    // (6-4)/2
+		{Purine, Pyrimidine, -4, -4, -4},
+		{Weak, T, 1, 6, 1},
+		{AnyBase, X, -3, -3, 0}, // an unknown base is not a gap
+		{C, G, -4, -4, -4},
+		{U, T, 6, 6, 5}, // RNA against DNA
+		{U, U, 6, 6, 5},
+		{U, A, -4, -4, -4},
+	}
+	for _, c := range cases {
+		if s := DefaultScoring.Pair(c.a, c.b); s != c.expected {
+			t.Errorf("%c%c expected match: %v, expected %v", c.a.Letter(), c.b.Letter(), s, c.expected)
+		}
+		if s := best.Pair(c.a, c.b); s != c.best {
+			t.Errorf("%c%c best match: %v, expected %v", c.a.Letter(), c.b.Letter(), s, c.best)
+		}
+		if s := nuc.Pair(c.a, c.b); s != c.nuc {
+			t.Errorf("%c%c NUC.4.4: %v, expected %v", c.a.Letter(), c.b.Letter(), s, c.nuc)
+		}
+	}
+	// U reads as T
+	if s := DefaultScoring.Pair(Weak, U); s != 1 {
+		t.Errorf("WU: %v, expected 1", s)
+	}
+	// arginine is not a purine
+	if p, _ := ParseSequence("R", Protein); p.Bases[0].Ambiguous() || DefaultScoring.Pair(p.Bases[0], A) != -4 {
+		t.Error("protein R read as an ambiguity code")
+	}
+}
@@ -0,0 +1,184 @@
+// Package fasta reads and writes sequences in the FASTA format.
+package fasta
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/internal/lineio"
+)
+
+// A FASTA file holds records, each a header line followed by sequence lines:
+//   >sp|P30304|MPIP1_HUMAN M-phase inducer phosphatase 1
+//   MELGPEPPHRRRLLFACSPPPASQPVVKALFGASAAGGLSPVTNLTVTMDQLQGLGSDYE
+//   ...
+// The first word of the header is the ID and the rest the description.
+// Sequence lines may be wrapped at any width. Blank lines and comment lines
+// starting with ';' are skipped.
+
+// DefaultLineWidth is the line width of a new Writer.
+const DefaultLineWidth = 60
+
+// Record is a named sequence.
+type Record struct {
+	ID          string
+	Description string
+	Seq         *bio.Sequence
+}
+
+// Header returns the header line of the record, without '>'.
+func (rec *Record) Header() string {
+	if rec.Description == "" {
+		return rec.ID
+	}
+	return rec.ID + " " + rec.Description
+}
+
+// Reader reads records one at a time.
+type Reader struct {
+	lines    *lineio.Reader
+	alphabet *bio.Alphabet
+	header   string // header of the next record, read with the previous one
+	started  bool
+}
+
+// NewReader returns a Reader of sequences of the alphabet a from r, which
+// may be gzip compressed.
+func NewReader(r io.Reader, a *bio.Alphabet) *Reader {
+	return &Reader{lines: lineio.NewReader(r), alphabet: a}
+}
+
+// Read returns the next record, io.EOF after the last one. Letters outside
+// the alphabet are an error.
+func (r *Reader) Read() (*Record, error) {
+	if !r.started {
+		// find the first header
+		for {
+			line, err := r.readLine()
+			if err != nil {
+				return nil, err
+			}
+			if line == "" {
+				continue
+			}
+			if line[0] != '>' {
+				return nil, fmt.Errorf("fasta: line %d: expected a header", r.lines.Line)
+			}
+			r.header, r.started = line, true
+			break
+		}
+	}
+	if r.header == "" {
+		return nil, io.EOF
+	}
+	rec := &Record{Seq: &bio.Sequence{Bases: []bio.Base{}, Alphabet: r.alphabet}}
+	header := strings.TrimSpace(r.header[1:])
+	rec.ID = header
+	if k := strings.IndexAny(header, " \t"); k >= 0 {
+		rec.ID, rec.Description = header[:k], strings.TrimSpace(header[k:])
+	}
+	r.header = ""
+	for {
+		line, err := r.readLine()
+		if err == io.EOF {
+			return rec, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			continue
+		}
+		if line[0] == '>' {
+			r.header = line
+			return rec, nil
+		}
+		for i := 0; i < len(line); i++ {
+			if c := line[i]; c == ' ' || c == '\t' {
+				continue
+			}
+			b, err := r.alphabet.Base(line[i])
+			if err != nil {
+				return nil, fmt.Errorf("fasta: line %d: %v", r.lines.Line, err)
+			}
+			rec.Seq.Bases = append(rec.Seq.Bases, b)
+		}
+	}
+}
+
+// ReadAll reads the remaining records.
+func (r *Reader) ReadAll() ([]*Record, error) {
+	var recs []*Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// readLine returns the next line without its line ending, skipping comment
+// lines, and "" for blank lines.
+func (r *Reader) readLine() (string, error) {
+	for {
+		line, err := r.lines.Next()
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			return "", nil
+		}
+		return line, nil
+	}
+}
+
+// Writer writes records.
+type Writer struct {
+	w io.Writer
+	// LineWidth is the number of letters per sequence line, no wrapping if
+	// zero or less.
+	LineWidth int
+}
+
+// NewWriter returns a Writer to w wrapping lines at DefaultLineWidth.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, LineWidth: DefaultLineWidth}
+}
+
+// Write writes a record.
+func (w *Writer) Write(rec *Record) error {
+	var b bytes.Buffer
+	b.WriteString(">" + rec.Header() + "\n")
+	seq := rec.Seq.String()
+	for len(seq) > 0 {
+		n := len(seq)
+		if w.LineWidth > 0 && n > w.LineWidth {
+			n = w.LineWidth
+		}
+		b.WriteString(seq[:n])
+		b.WriteByte('\n')
+		seq = seq[n:]
+	}
+	_, err := w.w.Write(b.Bytes())
+	return err
+}
+
+// WriteAll writes records.
+func (w *Writer) WriteAll(recs []*Record) error {
+	for _, rec := range recs {
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package fasta
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+const input = `; a comment
+>seq1 first sequence
+ACGT
+ACG
+
+>seq2
+>seq3	tab separated description
+nnAC
+  GT
+`
+
+func TestRead(t *testing.T) {
+	recs, err := NewReader(strings.NewReader(input), bio.IUPAC).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ id, desc, seq string }{
+		{"seq1", "first sequence", "ACGTACG"},
+		{"seq2", "", ""},
+		{"seq3", "tab separated description", "NNACGT"},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("%v records, expected %v", len(recs), len(want))
+	}
+	for i, w := range want {
+		rec := recs[i]
+		if rec.ID != w.id || rec.Description != w.desc || rec.Seq.String() != w.seq || rec.Seq.Alphabet != bio.IUPAC {
+			t.Errorf("record %v: %q %q %v", i, rec.ID, rec.Description, rec.Seq)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, bad := range []string{
+		"ACGT\n>seq\nACGT\n",
+		">seq\nACGX\n",
+	} {
+		if _, err := NewReader(strings.NewReader(bad), bio.DNA).ReadAll(); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+	if _, err := NewReader(strings.NewReader(""), bio.DNA).Read(); err != io.EOF {
+		t.Errorf("empty input: %v", err)
+	}
+}
+
+func TestGzipRoundTrip(t *testing.T) {
+	recs, err := NewReader(strings.NewReader(input), bio.IUPAC).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := NewWriter(gz)
+	w.LineWidth = 4
+	if err := w.WriteAll(recs); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+
+	var plain bytes.Buffer
+	w = NewWriter(&plain)
+	w.LineWidth = 4
+	w.WriteAll(recs)
+	if want := ">seq1 first sequence\nACGT\nACG\n>seq2\n>seq3 tab separated description\nNNAC\nGT\n"; plain.String() != want {
+		t.Errorf("wrote %q", plain.String())
+	}
+
+	again, err := NewReader(&buf, bio.IUPAC).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range recs {
+		if again[i].Header() != recs[i].Header() || again[i].Seq.String() != recs[i].Seq.String() {
+			t.Errorf("record %v: %v %v", i, again[i].Header(), again[i].Seq)
+		}
+	}
+}
+
+func TestUniProt(t *testing.T) {
+	f, err := os.Open("../seqcomp/2017-01-16uniprot.fasta")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer f.Close()
+	recs, err := NewReader(f, bio.Protein).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := recs[len(recs)-1]
+	if len(recs) != 1013 || !strings.HasPrefix(recs[0].ID, "sp|P30304|") || len(last.Seq.Bases) == 0 {
+		t.Errorf("%v records, first %v", len(recs), recs[0].ID)
+	}
+}
@@ -0,0 +1,252 @@
+// Package fastq reads and writes sequencing reads with quality scores in
+// the FASTQ format, and trims reads by quality.
+package fastq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/fasta"
+	"github.com/bsjcho/bioinf/internal/lineio"
+)
+
+// A FASTQ record is a header, the sequence, a separator and the qualities:
+//   @read1 description
+//   GATTTGGGGTTCAAAGCAGT
+//   +
+//   !''*((((***+))%%%++)
+// Qualities are Phred scores, -10 log10 of the probability that the base is
+// wrong, encoded as a character each by adding an offset: 33 for Sanger and
+// Illumina 1.8+, 64 for Illumina 1.3 to 1.7. Sequence and quality lines may
+// be wrapped, and blank lines between records are skipped.
+
+// Encoding is the offset of the quality characters.
+type Encoding int
+
+const (
+	// Auto detects the encoding from the first records.
+	Auto Encoding = 0
+	// Sanger is the encoding of Sanger and Illumina 1.8+ reads.
+	Sanger Encoding = 33
+	// Illumina13 is the encoding of Illumina 1.3 to 1.7 reads.
+	Illumina13 Encoding = 64
+)
+
+// detectRecords is the number of records read ahead to detect the encoding.
+const detectRecords = 1000
+
+// Record is a read with the Phred quality of every base.
+type Record struct {
+	ID          string
+	Description string
+	Seq         *bio.Sequence
+	Quality     []int
+}
+
+// Header returns the header line of the record, without '@'.
+func (rec *Record) Header() string {
+	if rec.Description == "" {
+		return rec.ID
+	}
+	return rec.ID + " " + rec.Description
+}
+
+// FASTA returns the record without qualities.
+func (rec *Record) FASTA() *fasta.Record {
+	return &fasta.Record{ID: rec.ID, Description: rec.Description, Seq: rec.Seq}
+}
+
+// raw is a record with its qualities still encoded.
+type raw struct {
+	rec     *Record
+	quality string
+	line    int // line of the quality
+}
+
+// Reader reads records one at a time.
+type Reader struct {
+	// Encoding of the qualities, Auto until the first record is read.
+	Encoding Encoding
+
+	lines    *lineio.Reader
+	alphabet *bio.Alphabet
+	ahead    []*raw // records read to detect the encoding
+}
+
+// NewReader returns a Reader of reads of the alphabet a from r, which may be
+// gzip compressed. With the Auto encoding, the offset is 64 if the first
+// records hold no quality below '@' but some above 'K', and 33 otherwise.
+func NewReader(r io.Reader, a *bio.Alphabet, enc Encoding) *Reader {
+	return &Reader{lines: lineio.NewReader(r), alphabet: a, Encoding: enc}
+}
+
+// Read returns the next record, io.EOF after the last one.
+func (r *Reader) Read() (*Record, error) {
+	if r.Encoding == Auto {
+		if err := r.detect(); err != nil {
+			return nil, err
+		}
+	}
+	var rr *raw
+	if len(r.ahead) > 0 {
+		rr, r.ahead = r.ahead[0], r.ahead[1:]
+	} else {
+		var err error
+		if rr, err = r.readRaw(); err != nil {
+			return nil, err
+		}
+	}
+	rec := rr.rec
+	rec.Quality = make([]int, len(rr.quality))
+	for i := 0; i < len(rr.quality); i++ {
+		q := int(rr.quality[i]) - int(r.Encoding)
+		if q < 0 || rr.quality[i] > '~' {
+			return nil, fmt.Errorf("fastq: line %d: quality %q out of range for offset %d",
+				rr.line, rr.quality[i], r.Encoding)
+		}
+		rec.Quality[i] = q
+	}
+	return rec, nil
+}
+
+// ReadAll reads the remaining records.
+func (r *Reader) ReadAll() ([]*Record, error) {
+	var recs []*Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// detect reads records ahead to set the encoding from their qualities.
+func (r *Reader) detect() error {
+	min, max := byte('~'), byte(0)
+	for len(r.ahead) < detectRecords {
+		rr, err := r.readRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		r.ahead = append(r.ahead, rr)
+		for i := 0; i < len(rr.quality); i++ {
+			c := rr.quality[i]
+			if c < min {
+				min = c
+			}
+			if c > max {
+				max = c
+			}
+		}
+	}
+	r.Encoding = Sanger
+	if min >= '@' && max > 'K' {
+		r.Encoding = Illumina13
+	}
+	return nil
+}
+
+// readRaw reads the lines of a record.
+func (r *Reader) readRaw() (*raw, error) {
+	header, err := r.lines.Next()
+	for err == nil && header == "" {
+		header, err = r.lines.Next()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if header[0] != '@' {
+		return nil, fmt.Errorf("fastq: line %d: expected a header", r.lines.Line)
+	}
+	rec := &Record{Seq: &bio.Sequence{Bases: []bio.Base{}, Alphabet: r.alphabet}}
+	header = strings.TrimSpace(header[1:])
+	rec.ID = header
+	if k := strings.IndexAny(header, " \t"); k >= 0 {
+		rec.ID, rec.Description = header[:k], strings.TrimSpace(header[k:])
+	}
+	// sequence lines up to the separator
+	for {
+		line, err := r.lines.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("fastq: line %d: missing '+' separator", r.lines.Line)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(line, "+") {
+			break
+		}
+		for i := 0; i < len(line); i++ {
+			b, err := r.alphabet.Base(line[i])
+			if err != nil {
+				return nil, fmt.Errorf("fastq: line %d: %v", r.lines.Line, err)
+			}
+			rec.Seq.Bases = append(rec.Seq.Bases, b)
+		}
+	}
+	// quality lines up to the length of the sequence; '@' may start a
+	// quality line, so lines are counted rather than looked at
+	rr := &raw{rec: rec}
+	for len(rr.quality) < len(rec.Seq.Bases) {
+		line, err := r.lines.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rr.quality += line
+		rr.line = r.lines.Line
+	}
+	if len(rr.quality) != len(rec.Seq.Bases) {
+		return nil, fmt.Errorf("fastq: line %d: %d qualities for %d bases",
+			r.lines.Line, len(rr.quality), len(rec.Seq.Bases))
+	}
+	return rr, nil
+}
+
+// Writer writes records.
+type Writer struct {
+	w io.Writer
+	// Encoding of the qualities, Sanger if Auto.
+	Encoding Encoding
+}
+
+// NewWriter returns a Writer to w with the Sanger encoding.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, Encoding: Sanger}
+}
+
+// Write writes a record on four lines.
+func (w *Writer) Write(rec *Record) error {
+	enc := w.Encoding
+	if enc == Auto {
+		enc = Sanger
+	}
+	if len(rec.Quality) != len(rec.Seq.Bases) {
+		return fmt.Errorf("fastq: %s has %d qualities for %d bases",
+			rec.ID, len(rec.Quality), len(rec.Seq.Bases))
+	}
+	var b bytes.Buffer
+	b.WriteString("@" + rec.Header() + "\n" + rec.Seq.String() + "\n+\n")
+	for _, q := range rec.Quality {
+		c := q + int(enc)
+		if q < 0 || c > '~' {
+			return fmt.Errorf("fastq: %s: quality %d out of range for offset %d", rec.ID, q, enc)
+		}
+		b.WriteByte(byte(c))
+	}
+	b.WriteByte('\n')
+	_, err := w.w.Write(b.Bytes())
+	return err
+}
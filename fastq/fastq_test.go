@@ -0,0 +1,150 @@
+package fastq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+const sanger = `@read1 first read
+ACGTN
++read1
+!+5?I
+
+@read2
+ACGT
+ACGT
++
+@@@@
+IIII
+`
+
+func TestRead(t *testing.T) {
+	r := NewReader(strings.NewReader(sanger), bio.IUPAC, Auto)
+	recs, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Encoding != Sanger || len(recs) != 2 {
+		t.Fatalf("encoding %v, %v records", r.Encoding, len(recs))
+	}
+	if recs[0].ID != "read1" || recs[0].Description != "first read" || recs[0].Seq.String() != "ACGTN" ||
+		!reflect.DeepEqual(recs[0].Quality, []int{0, 10, 20, 30, 40}) {
+		t.Errorf("read1: %v %v %v", recs[0].Header(), recs[0].Seq, recs[0].Quality)
+	}
+	// a quality line may start with '@'
+	if recs[1].Seq.String() != "ACGTACGT" || !reflect.DeepEqual(recs[1].Quality, []int{31, 31, 31, 31, 40, 40, 40, 40}) {
+		t.Errorf("read2: %v %v", recs[1].Seq, recs[1].Quality)
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	illumina := "@r\nACGT\n+\n@JTh\n"
+	r := NewReader(strings.NewReader(illumina), bio.DNA, Auto)
+	rec, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Encoding != Illumina13 || !reflect.DeepEqual(rec.Quality, []int{0, 10, 20, 40}) {
+		t.Errorf("encoding %v, qualities %v", r.Encoding, rec.Quality)
+	}
+	// the same characters read as Sanger when told so
+	rec, err = NewReader(strings.NewReader(illumina), bio.DNA, Sanger).Read()
+	if err != nil || rec.Quality[0] != 31 {
+		t.Errorf("Sanger: %v %v", rec, err)
+	}
+	// but ! is below the Illumina offset
+	if _, err := NewReader(strings.NewReader(sanger), bio.IUPAC, Illumina13).Read(); err == nil {
+		t.Error("expected a quality error")
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, bad := range []string{
+		"ACGT\n+\nIIII\n",
+		"@r\nACGT\nIIII\n",
+		"@r\nACGT\n+\nIII\n",
+		"@r\nACGZ\n+\nIIII\n",
+	} {
+		if _, err := NewReader(strings.NewReader(bad), bio.DNA, Auto).ReadAll(); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+	if _, err := NewReader(strings.NewReader(""), bio.DNA, Auto).Read(); err != io.EOF {
+		t.Errorf("empty input: %v", err)
+	}
+}
+
+func TestWriteGzip(t *testing.T) {
+	recs, err := NewReader(strings.NewReader(sanger), bio.IUPAC, Auto).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := NewWriter(gz)
+	w.Encoding = Illumina13
+	for _, rec := range recs {
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gz.Close()
+	again, err := NewReader(&buf, bio.IUPAC, Illumina13).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range recs {
+		if again[i].Header() != recs[i].Header() || !reflect.DeepEqual(again[i].Quality, recs[i].Quality) {
+			t.Errorf("record %v: %v %v", i, again[i].Header(), again[i].Quality)
+		}
+	}
+
+	var plain bytes.Buffer
+	NewWriter(&plain).Write(recs[0])
+	if plain.String() != "@read1 first read\nACGTN\n+\n!+5?I\n" {
+		t.Errorf("wrote %q", plain.String())
+	}
+	if err := NewWriter(&plain).Write(&Record{ID: "r", Seq: parse(t, "AC"), Quality: []int{1}}); err == nil {
+		t.Error("expected a length error")
+	}
+}
+
+func TestTrim(t *testing.T) {
+	rec := &Record{
+		ID:      "r",
+		Seq:     parse(t, "ACGTACGTAC"),
+		Quality: []int{2, 30, 30, 30, 30, 30, 10, 5, 30, 2},
+	}
+	if got := rec.TrimEnds(3, 3); got.Seq.String() != "CGTACGTA" || len(got.Quality) != 8 {
+		t.Errorf("TrimEnds: %v %v", got.Seq, got.Quality)
+	}
+	// the window 30, 30, 10, 5 from position 4 averages 18.75
+	if got := rec.SlidingWindow(4, 20); got.Seq.String() != "ACGT" {
+		t.Errorf("SlidingWindow: %v", got.Seq)
+	}
+	if got := rec.SlidingWindow(4, 1); got.Seq.String() != "ACGTACGTAC" {
+		t.Errorf("SlidingWindow keeping all: %v", got.Seq)
+	}
+	if got := rec.TrimEnds(100, 0); len(got.Seq.Bases) != 0 {
+		t.Errorf("TrimEnds everything: %v", got.Seq)
+	}
+	if got := rec.FASTA(); got.ID != "r" || got.Seq != rec.Seq {
+		t.Errorf("FASTA: %v", got)
+	}
+}
+
+// parse reads a DNA sequence, failing the test on other letters.
+func parse(t *testing.T, s string) *bio.Sequence {
+	t.Helper()
+	seq, err := bio.ParseSequence(s, bio.DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seq
+}
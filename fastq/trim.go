@@ -0,0 +1,63 @@
+package fastq
+
+import bio "github.com/bsjcho/bioinf"
+
+// Quality trimming, after Trimmomatic (Bolger et al.).
+// Input:
+// - A read with Phred qualities
+// Output:
+// - The part of the read kept, sharing the bases and qualities of the input
+//
+// TrimEnds removes bases below a quality from either end, as LEADING and
+// TRAILING. SlidingWindow scans the read from its 5' end and cuts it at the
+// first window whose mean quality falls below a threshold, as SLIDINGWINDOW.
+
+// Trim returns the bases from start to end, not included.
+func (rec *Record) Trim(start, end int) *Record {
+	return &Record{
+		ID:          rec.ID,
+		Description: rec.Description,
+		Seq:         &bio.Sequence{Bases: rec.Seq.Bases[start:end], Alphabet: rec.Seq.Alphabet},
+		Quality:     rec.Quality[start:end],
+	}
+}
+
+// TrimEnds removes the bases of quality below leading from the start of the
+// read and those below trailing from its end.
+func (rec *Record) TrimEnds(leading, trailing int) *Record {
+	start, end := 0, len(rec.Quality)
+	for start < end && rec.Quality[start] < leading {
+		start++
+	}
+	for end > start && rec.Quality[end-1] < trailing {
+		end--
+	}
+	return rec.Trim(start, end)
+}
+
+// SlidingWindow cuts the read at the start of the first window of size
+// bases with a mean quality below minMean. Reads shorter than the window
+// are judged on their own mean.
+func (rec *Record) SlidingWindow(size int, minMean float64) *Record {
+	n := len(rec.Quality)
+	if size > n {
+		size = n
+	}
+	if size <= 0 {
+		return rec.Trim(0, n)
+	}
+	sum := 0
+	for _, q := range rec.Quality[:size] {
+		sum += q
+	}
+	threshold := minMean * float64(size)
+	for start := 0; start+size <= n; start++ {
+		if start > 0 {
+			sum += rec.Quality[start+size-1] - rec.Quality[start-1]
+		}
+		if float64(sum) < threshold {
+			return rec.Trim(0, start)
+		}
+	}
+	return rec.Trim(0, n)
+}
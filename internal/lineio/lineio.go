@@ -0,0 +1,55 @@
+// Package lineio reads the lines of text formats for the format packages.
+package lineio
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+// Reader reads lines, counting them for error messages.
+type Reader struct {
+	src io.Reader
+	r   *bufio.Reader
+	// Line is the number of the last line read.
+	Line int
+}
+
+// NewReader returns a Reader of the lines of r, which may be gzip
+// compressed.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{src: r}
+}
+
+// Next returns the next line without its line ending, io.EOF after the
+// last one.
+func (r *Reader) Next() (string, error) {
+	if r.r == nil {
+		if err := r.open(); err != nil {
+			return "", err
+		}
+	}
+	line, err := r.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	r.Line++
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// open wraps the source, decompressing it if it starts with the gzip magic
+// number.
+func (r *Reader) open() error {
+	br := bufio.NewReader(r.src)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		br = bufio.NewReader(gz)
+	}
+	r.r = br
+	return nil
+}
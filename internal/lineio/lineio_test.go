@@ -0,0 +1,34 @@
+package lineio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	const text = "first\r\nsecond\n\nlast"
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(text))
+	gz.Close()
+	for _, src := range []io.Reader{strings.NewReader(text), &buf} {
+		r := NewReader(src)
+		var lines []string
+		for {
+			line, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, line)
+		}
+		if strings.Join(lines, "|") != "first|second||last" || r.Line != 4 {
+			t.Errorf("read %q, %d lines", lines, r.Line)
+		}
+	}
+}
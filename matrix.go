@@ -0,0 +1,147 @@
+package bioinf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SubstitutionMatrix scores pairs of residues, such as BLOSUM62 for
+// proteins or NUC44 for nucleotides.
+type SubstitutionMatrix struct {
+	Name string
+	// Letters are the residues of the rows and columns, in order.
+	Letters string
+	scores  [][]int
+	index   [256]int // row of every letter, -1 if absent
+	other   int      // row scoring letters absent from the matrix
+}
+
+// ParseMatrix reads a substitution matrix in the NCBI text format: lines
+// starting with '#' are comments, a header line lists the column letters,
+// then every row starts with its letter followed by a score per column.
+// Letters are case insensitive.
+func ParseMatrix(name string, r io.Reader) (*SubstitutionMatrix, error) {
+	m := &SubstitutionMatrix{Name: name}
+	for i := range m.index {
+		m.index[i] = -1
+	}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if m.Letters == "" {
+			for _, f := range fields {
+				if len(f) != 1 {
+					return nil, fmt.Errorf("bioinf: matrix line %d: bad column letter %q", line, f)
+				}
+				m.Letters += strings.ToUpper(f)
+			}
+			continue
+		}
+		if len(fields[0]) != 1 || len(fields) != len(m.Letters)+1 {
+			return nil, fmt.Errorf("bioinf: matrix line %d: expected a letter and %d scores",
+				line, len(m.Letters))
+		}
+		row := make([]int, len(m.Letters))
+		for k, f := range fields[1:] {
+			score, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("bioinf: matrix line %d: %v", line, err)
+			}
+			row[k] = score
+		}
+		letter := strings.ToUpper(fields[0])[0]
+		if len(m.scores) == len(m.Letters) || m.Letters[len(m.scores)] != letter {
+			return nil, fmt.Errorf("bioinf: matrix line %d: row %c out of the order of the columns",
+				line, letter)
+		}
+		m.scores = append(m.scores, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if m.Letters == "" || len(m.scores) != len(m.Letters) {
+		return nil, fmt.Errorf("bioinf: matrix %s has %d rows for %d columns",
+			name, len(m.scores), len(m.Letters))
+	}
+	for k := range m.Letters {
+		m.index[m.Letters[k]] = k
+		m.index[strings.ToLower(m.Letters[k : k+1])[0]] = k
+	}
+	// U reads as T in a nucleotide matrix without U, as DNA matrices are
+	// used for RNA
+	if m.index['U'] < 0 && m.index['T'] >= 0 && strings.Trim(m.Letters, IUPAC.Letters) == "" {
+		m.index['U'], m.index['u'] = m.index['T'], m.index['T']
+	}
+	// letters missing from the matrix score like the wildcard
+	m.other = -1
+	for _, wildcard := range []byte("XN*") {
+		if k := m.index[wildcard]; k >= 0 {
+			m.other = k
+			break
+		}
+	}
+	return m, nil
+}
+
+func mustParseMatrix(name, text string) *SubstitutionMatrix {
+	m, err := ParseMatrix(name, strings.NewReader(text))
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Score returns the score of the residues a and b. A letter absent from the
+// matrix scores as its wildcard (X, N or *, the first found), or 0 if it has
+// none, except U scoring as T in nucleotide matrices.
+func (m *SubstitutionMatrix) Score(a, b byte) int {
+	i, j := m.index[a], m.index[b]
+	if i < 0 {
+		i = m.other
+	}
+	if j < 0 {
+		j = m.other
+	}
+	if i < 0 || j < 0 {
+		return 0
+	}
+	return m.scores[i][j]
+}
+
+// Substitution scores a pair of bases by their letters, for use as
+// ScoringScheme.Substitution.
+func (m *SubstitutionMatrix) Substitution(a, b Base) int {
+	return m.Score(a.Letter(), b.Letter())
+}
+
+// Scheme returns the scoring scheme using m with the gap costs g, in the
+// units of the matrix. The matrix scores ambiguity codes itself
+// (LiteralMatch), set ExpectedMatch for a matrix of A, C, G and T only.
+func (m *SubstitutionMatrix) Scheme(g GapCosts) ScoringScheme {
+	return ScoringScheme{Substitution: m.Substitution, Gaps: g, Scale: 1, Ambiguity: LiteralMatch}
+}
+
+// String returns the matrix in the format read by ParseMatrix.
+func (m *SubstitutionMatrix) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n ", m.Name)
+	for k := range m.Letters {
+		fmt.Fprintf(&b, " %3c", m.Letters[k])
+	}
+	for i, row := range m.scores {
+		fmt.Fprintf(&b, "\n%c", m.Letters[i])
+		for _, score := range row {
+			fmt.Fprintf(&b, " %3d", score)
+		}
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
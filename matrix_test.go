@@ -0,0 +1,82 @@
+package bioinf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinMatrices(t *testing.T) {
+	for name, m := range Matrices {
+		for i := range m.Letters {
+			for j := range m.Letters {
+				a, b := m.Letters[i], m.Letters[j]
+				if m.Score(a, b) != m.Score(b, a) {
+					t.Errorf("%v: %c%c not symmetric", name, a, b)
+				}
+			}
+		}
+	}
+	cases := []struct {
+		m     *SubstitutionMatrix
+		a, b  byte
+		score int
+	}{
+		{BLOSUM62, 'W', 'W', 11},
+		{BLOSUM62, 'A', 'R', -1},
+		{BLOSUM62, 'i', 'v', 3},
+		{BLOSUM45, 'C', 'C', 12},
+		{BLOSUM80, 'P', 'P', 8},
+		{PAM30, 'W', 'A', -13},
+		{PAM70, 'M', 'M', 10},
+		{PAM250, 'W', 'W', 17},
+		{NUC44, 'A', 'A', 5},
+		{NUC44, 'A', 'R', 1},
+		// letters absent from the matrix score as X or N
+		{BLOSUM62, 'U', 'A', 0},
+		{NUC44, 'X', 'A', -2},
+		// but U is T in a nucleotide matrix
+		{NUC44, 'U', 'U', 5},
+		{NUC44, 'u', 'A', -4},
+		{NUC44, 'U', 'T', 5},
+	}
+	for _, c := range cases {
+		if s := c.m.Score(c.a, c.b); s != c.score {
+			t.Errorf("%v %c%c: %v, expected %v", c.m.Name, c.a, c.b, s, c.score)
+		}
+	}
+	if NUC44.Score('U', 'U') != NUC44.Score('T', 'T') || NUC44.Substitution(U, G) != NUC44.Substitution(T, G) {
+		t.Error("NUC44 scores U unlike T")
+	}
+}
+
+func TestParseMatrix(t *testing.T) {
+	m, err := ParseMatrix("copy", strings.NewReader(BLOSUM62.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Letters != BLOSUM62.Letters || m.Score('W', 'Y') != 2 {
+		t.Errorf("round trip: %v", m)
+	}
+	for _, bad := range []string{
+		"",
+		"  A C\nA 1 0\n",
+		"  A C\nA 1 0\nC 0\n",
+		"  A C\nC 0 1\nA 1 0\n",
+		"  A C\nA 1 x\nC 0 1\n",
+	} {
+		if _, err := ParseMatrix("bad", strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestMatrixScheme(t *testing.T) {
+	s := NUC44.Scheme(GapCosts{Open: -10, Extend: -1})
+	seqs, err := ParseSequences([]string{"ACG-T", "ACGAT"}, DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score := s.Float(SPScore(seqs, s)); score != 9 {
+		t.Errorf("score %v, expected 9", score)
+	}
+}
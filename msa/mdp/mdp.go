@@ -1,7 +1,10 @@
 package mdp
 
 import (
+	"math"
+
 	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/alignment"
 	"github.com/bsjcho/nd"
 )
 
@@ -11,15 +14,30 @@ type multiDP struct {
 	cached *nd.Array       // to determine if an optimal score has already been
 	// calculated. necessary for memoization since scores can be 0
 	subsetMasks [][]int
+	scoring     bio.ScoringScheme
+	global      bool // score the whole sequences, see SolveGlobal
 }
 
-func newMultiDP(s []*bio.Sequence) *multiDP {
-	return &multiDP{
+func newMultiDP(s []*bio.Sequence, scoring bio.ScoringScheme, global bool) *multiDP {
+	m := &multiDP{
 		seqs:        s,
-		table:       nd.NewArray(sizes(s)),
-		cached:      nd.NewArray(sizes(s)),
 		subsetMasks: generateSubsetMasks(len(s)),
+		scoring:     scoring,
+		global:      global,
+	}
+	tableSizes := sizes(s)
+	if m.affine() {
+		// the score of a column depends on the gaps of the previous one, so
+		// the table gains a dimension holding the mask of the last column.
+		tableSizes = append(tableSizes, len(m.subsetMasks))
 	}
+	m.table = nd.NewArray(tableSizes)
+	m.cached = nd.NewArray(tableSizes)
+	return m
+}
+
+func (m *multiDP) affine() bool {
+	return m.scoring.Gaps.Open != 0
 }
 
 /*
@@ -45,33 +63,69 @@ func generateSubsetMasks(numSeqCompared int) [][]int {
 	return findSubsets(x)
 }
 
-// Solve takes in a list of sequences and returns score of the optimal alignment
-func Solve(seqStrings []string) float64 {
-	mdp := newMultiDP(bio.AsToSeqs(seqStrings))
+// Solve takes in a list of sequences and returns score of the optimal
+// alignment under the scoring scheme s (see bio.SPScore). Leading
+// overhangs are free: once a sequence is used up, the prefixes left of the
+// others are not scored, and neither are prefixes scoring below zero.
+func Solve(seqs []*bio.Sequence, s bio.ScoringScheme) float64 {
+	mdp := newMultiDP(seqs, s, false)
 	return mdp.solve()
 }
 
+// SolveGlobal is Solve scoring the whole sequences, overhangs included:
+// the SP score of an optimal alignment.
+func SolveGlobal(seqs []*bio.Sequence, s bio.ScoringScheme) float64 {
+	mdp := newMultiDP(seqs, s, true)
+	return mdp.solve()
+}
+
+// Align is SolveGlobal returning an optimal alignment as well. Its SP
+// score is the returned score.
+func Align(seqs []*bio.Sequence, s bio.ScoringScheme) (float64, *alignment.Alignment) {
+	mdp := newMultiDP(seqs, s, true)
+	score := mdp.solve()
+	var cols [][]bio.Base
+	if mdp.affine() {
+		cols = mdp.affineTraceback(mdp.maxIndices())
+	} else {
+		cols = mdp.traceback(mdp.maxIndices())
+	}
+	// columns were found from the end
+	aligned := make([]*bio.Sequence, len(seqs))
+	for i, seq := range seqs {
+		aligned[i] = &bio.Sequence{Bases: make([]bio.Base, len(cols)), Alphabet: seq.Alphabet}
+		for k, col := range cols {
+			aligned[i].Bases[len(cols)-1-k] = col[i]
+		}
+	}
+	a, _ := alignment.New(nil, aligned)
+	return score, a
+}
+
 func (m *multiDP) solve() float64 {
-	optScore := m.optimalScore(m.maxIndices())
-	// values doubled to be able to use integers during calculations
-	// final result is converted to float then divided by two
-	// see const block declared above
-	return float64(optScore) / 2
+	var optScore int
+	if m.affine() {
+		optScore = m.optimalAffineScore(m.maxIndices())
+	} else {
+		optScore = m.optimalScore(m.maxIndices())
+	}
+	return m.scoring.Float(optScore)
 }
 
 // uses memoization as opposed to tabulation/dp
 // represents optimal score function F(i1, i2, i3, ... , in)
 func (m *multiDP) optimalScore(idxs []int) (best int) {
 	// base case
-	for _, i := range idxs {
-		if i <= 0 {
-			return
-		}
+	if m.base(idxs) {
+		return 0
 	}
 	// have we calculated the score for these indices before?
 	if m.cached.At(idxs) == 1 {
 		return m.table.At(idxs)
 	}
+	if m.global {
+		best = math.MinInt64
+	}
 	// see generateSubsetMasks() comment for explaination of subset masks
 	// iterate over all possible masks to find the optimal score
 	for _, mask := range m.subsetMasks {
@@ -89,7 +143,7 @@ func (m *multiDP) optimalScore(idxs []int) (best int) {
 		// and the mask.
 		bases := m.maskedBases(idxs, mask)
 		// calculate the score of this column of bases (and gaps) using sum-of-pairs
-		score := bio.ColumnSPScore(bases)
+		score := bio.ColumnSPScore(bases, nil, m.scoring)
 
 		// maintain best score
 		best = bio.Max(best, optScore+score)
@@ -101,10 +155,145 @@ func (m *multiDP) optimalScore(idxs []int) (best int) {
 	return
 }
 
+// represents the optimal score function with affine gaps,
+// the max over the mask of the last column of F(i1, ..., in, mask)
+func (m *multiDP) optimalAffineScore(idxs []int) (best int) {
+	if m.base(idxs) {
+		return 0
+	}
+	if m.global {
+		best = math.MinInt64
+	}
+	for last := range m.subsetMasks {
+		if score, ok := m.affineScore(idxs, last); ok {
+			best = bio.Max(best, score)
+		}
+	}
+	return
+}
+
+// affineScore is F(i1, ..., in, last): the optimal score of the alignments
+// whose last column has the mask subsetMasks[last]. ok is false if that
+// column cannot end the alignment of these prefixes.
+func (m *multiDP) affineScore(idxs []int, last int) (best int, ok bool) {
+	key := append(cpy(idxs), last)
+	if m.cached.At(key) == 1 {
+		return m.table.At(key), true
+	}
+	mask := m.subsetMasks[last]
+	mIdxs, ok := maskedIdxs(idxs, mask)
+	if !ok {
+		return 0, false
+	}
+	bases := m.maskedBases(idxs, mask)
+	// the column starts the alignment after the base case, or after
+	// prefixes dropped as in optimalScore's floor
+	best = bio.ColumnSPScore(bases, nil, m.scoring)
+	if !m.base(mIdxs) {
+		if m.global {
+			best = math.MinInt64
+		}
+		for prev, prevMask := range m.subsetMasks {
+			score, ok := m.affineScore(mIdxs, prev)
+			if !ok {
+				continue
+			}
+			prevBases := m.maskedBases(mIdxs, prevMask)
+			best = bio.Max(best, score+bio.ColumnSPScore(bases, prevBases, m.scoring))
+		}
+	}
+	m.table.Set(best, key)
+	m.cached.Set(1, key)
+	return best, true
+}
+
+// traceback returns the columns of an optimal alignment of the prefixes
+// idxs, last column first, following optimalScore
+func (m *multiDP) traceback(idxs []int) (cols [][]bio.Base) {
+	for !exhausted(idxs) {
+		best := m.optimalScore(idxs)
+		for _, mask := range m.subsetMasks {
+			mIdxs, ok := maskedIdxs(idxs, mask)
+			if !ok {
+				continue
+			}
+			bases := m.maskedBases(idxs, mask)
+			if m.optimalScore(mIdxs)+bio.ColumnSPScore(bases, nil, m.scoring) == best {
+				cols = append(cols, bases)
+				idxs = mIdxs
+				break
+			}
+		}
+	}
+	return
+}
+
+// affineTraceback is traceback following affineScore
+func (m *multiDP) affineTraceback(idxs []int) (cols [][]bio.Base) {
+	if exhausted(idxs) {
+		return
+	}
+	best := m.optimalAffineScore(idxs)
+	last := 0
+	for l := range m.subsetMasks {
+		if score, ok := m.affineScore(idxs, l); ok && score == best {
+			last = l
+			break
+		}
+	}
+	for {
+		score, _ := m.affineScore(idxs, last)
+		mIdxs, _ := maskedIdxs(idxs, m.subsetMasks[last])
+		bases := m.maskedBases(idxs, m.subsetMasks[last])
+		cols = append(cols, bases)
+		if exhausted(mIdxs) {
+			return
+		}
+		for prev, prevMask := range m.subsetMasks {
+			prevScore, ok := m.affineScore(mIdxs, prev)
+			if !ok {
+				continue
+			}
+			prevBases := m.maskedBases(mIdxs, prevMask)
+			if prevScore+bio.ColumnSPScore(bases, prevBases, m.scoring) == score {
+				last = prev
+				break
+			}
+		}
+		idxs = mIdxs
+	}
+}
+
 /////////////////////////
 // Helper Functions
+
 /////////////////////////
 
+// base reports whether idxs is a base case of the optimal score functions:
+// every prefix is empty for a global score, any prefix otherwise.
+func (m *multiDP) base(idxs []int) bool {
+	if m.global {
+		return exhausted(idxs)
+	}
+	for _, i := range idxs {
+		if i <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// exhausted reports whether every prefix is empty, the base case of the
+// optimal score functions
+func exhausted(idxs []int) bool {
+	for _, i := range idxs {
+		if i > 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func sizes(s []*bio.Sequence) (sizes []int) {
 	for _, seq := range s {
 		sizes = append(sizes, len(seq.Bases)+1)
@@ -2,7 +2,10 @@ package mdp
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+
+	bio "github.com/bsjcho/bioinf"
 )
 
 const (
@@ -34,7 +37,7 @@ func TestGenSubMasks(t *testing.T) {
 }
 
 func TestMDP(t *testing.T) {
-	optScore := Solve([]string{x1, x2, x3, x4})
+	optScore := Solve(parse(t, x1, x2, x3, x4), bio.DefaultScoring)
 	t.Log(optScore)
 	if optScore != 45 {
 		t.Error("Incorrect score.")
@@ -42,9 +45,114 @@ func TestMDP(t *testing.T) {
 }
 
 func TestMDPSimple(t *testing.T) {
-	optScore := Solve([]string{x5, x6, x7, x8})
+	optScore := Solve(parse(t, x5, x6, x7, x8), bio.DefaultScoring)
 	t.Log(optScore)
 	if optScore != 36 {
 		t.Error("Incorrect score.")
 	}
 }
+
+func TestMDPAffine(t *testing.T) {
+	seqs := parse(t, x1, x2, x3, x4)
+	linear := Solve(seqs, bio.DefaultScoring)
+	affine := bio.DefaultScoring
+	affine.Gaps = bio.GapCosts{Open: -10, Extend: -3}
+	if s := Solve(seqs, affine); s >= linear {
+		t.Errorf("affine score %v not below linear %v", s, linear)
+	}
+	// leading overhangs are free: ACGT against AAGT
+	affine.Gaps = bio.GapCosts{Open: -10, Extend: -1}
+	if s := Solve(parse(t, "AACGT", "AAGT"), affine); s != 7 {
+		t.Errorf("pair: %v, expected 7", s)
+	}
+	if s := SolveGlobal(parse(t, "AACGT", "AAGT"), affine); s != 6.5 {
+		t.Errorf("global pair: %v, expected 6.5", s)
+	}
+}
+
+// TestLinearAffine checks that the affine recurrence with no gap opening
+// cost scores as the linear one.
+func TestLinearAffine(t *testing.T) {
+	for _, strs := range [][]string{{"A", "C"}, {"AAAC", "GGGA"}, {x1, x2, x3}, {x5, "C", x7}} {
+		seqs := parse(t, strs...)
+		for _, global := range []bool{false, true} {
+			s := bio.DefaultScoring
+			s.Gaps.Open = -1
+			m := newMultiDP(seqs, s, global)
+			m.scoring.Gaps.Open = 0
+			affine := m.scoring.Float(m.optimalAffineScore(m.maxIndices()))
+			linear := newMultiDP(seqs, bio.DefaultScoring, global).solve()
+			if affine != linear {
+				t.Errorf("%v, global %v: affine %v, linear %v", strs, global, affine, linear)
+			}
+		}
+	}
+	// the score is floored at zero, unless global
+	seqs := parse(t, "A", "C")
+	if s, g := Solve(seqs, bio.DefaultScoring), SolveGlobal(seqs, bio.DefaultScoring); s != 0 || g != -2 {
+		t.Errorf("A against C: %v and global %v, expected 0 and -2", s, g)
+	}
+}
+
+func TestMDPProtein(t *testing.T) {
+	seqs, err := bio.ParseSequences([]string{"HEAGAWGHEE", "PAWHEAE"}, bio.Protein)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := Solve(seqs, bio.BLOSUM62.Scheme(bio.GapCosts{Extend: -1})); s != 31 {
+		t.Errorf("protein score %v, expected 31", s)
+	}
+}
+
+func TestMDPScheme(t *testing.T) {
+	// with unit scores the pair score is the length of a longest common
+	// subsequence, AATTTGG
+	lcs := bio.ScoringScheme{Substitution: bio.MatchMismatch(1, -100)}
+	if s := Solve(parse(t, x1, x4), lcs); s != 7 {
+		t.Errorf("common subsequence score %v, expected 7", s)
+	}
+}
+
+func TestAlign(t *testing.T) {
+	seqs := parse(t, x5, x6, x7, x8)
+	score, a := Align(seqs, bio.DefaultScoring)
+	if score != 36 || a.Columns() != 2 || bio.DefaultScoring.Float(bio.SPScore(a.Seqs, bio.DefaultScoring)) != 36 {
+		t.Errorf("%v %v", score, a.Seqs)
+	}
+
+	affine := bio.DefaultScoring
+	affine.Gaps = bio.GapCosts{Open: -10, Extend: -3}
+	for _, s := range []bio.ScoringScheme{bio.DefaultScoring, affine} {
+		seqs := parse(t, x1, x2, x3, x4)
+		score, a := Align(seqs, s)
+		if score != SolveGlobal(seqs, s) {
+			t.Errorf("Align score %v, SolveGlobal %v", score, SolveGlobal(seqs, s))
+		}
+		for i, seq := range a.Seqs {
+			if len(seq.Bases) != a.Columns() || strings.Replace(seq.String(), "-", "", -1) != seqs[i].String() {
+				t.Fatalf("sequence %v aligned as %v", seqs[i], seq)
+			}
+		}
+	}
+
+	// the alignment scores as Align, overhangs included
+	for _, strs := range [][]string{{"ACGTACGT", "ACGACGT"}, {"AAAC", "GGGA"}, {x1, "GG", x3}} {
+		seqs = parse(t, strs...)
+		for _, s := range []bio.ScoringScheme{bio.DefaultScoring, affine} {
+			score, a = Align(seqs, s)
+			if sp := s.Float(bio.SPScore(a.Seqs, s)); sp != score {
+				t.Errorf("alignment %v scores %v, Align %v", a.Seqs, sp, score)
+			}
+		}
+	}
+}
+
+// parse reads DNA sequences, failing the test on other letters.
+func parse(t *testing.T, strs ...string) []*bio.Sequence {
+	t.Helper()
+	seqs, err := bio.ParseSequences(strs, bio.DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seqs
+}
@@ -0,0 +1,189 @@
+package pairwise
+
+import (
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/alignment"
+)
+
+// Pairwise sequence alignment.
+// Input:
+// - Two sequences
+// - A scoring scheme, for pairs of bases and gap costs
+// Output:
+// - The score of an optimal alignment
+// - The aligned sequences, with gaps (bio.X) inserted
+//
+// With affine gaps (Gotoh) the best score of a[:i] against b[:j] is kept
+// separately for alignments ending with a gap in b (E) or in a (F):
+//   E(i,j) = max( H(i-1,j) + open + extend, E(i-1,j) + extend )
+//   F(i,j) = max( H(i,j-1) + open + extend, F(i,j-1) + extend )
+//   H(i,j) = max( H(i-1,j-1) + s(a_i, b_j), E(i,j), F(i,j) )
+// NeedlemanWunsch aligns the sequences end to end.
+// SmithWaterman finds the best scoring pair of substrings, adding 0 to the
+// max of H so that an alignment can start anywhere, and ending at the best
+// cell.
+
+// Result is an alignment of two sequences.
+type Result struct {
+	// Score of the alignment
+	Score float64
+	// A and B are the aligned parts of the sequences, of equal length,
+	// with gaps inserted.
+	A, B *bio.Sequence
+	// AStart and BStart are the positions of the first aligned bases in
+	// the input sequences, zero for global alignments.
+	AStart, BStart int
+}
+
+// neg stands for minus infinity, leaving room to add scores to it
+const neg = math.MinInt64 / 4
+
+// Alignment returns the aligned sequences as an alignment naming them
+// nameA and nameB, for writing in any of the formats of package alignment.
+func (r *Result) Alignment(nameA, nameB string) *alignment.Alignment {
+	return &alignment.Alignment{
+		Names:  []string{nameA, nameB},
+		Seqs:   []*bio.Sequence{r.A, r.B},
+		Starts: []int{r.AStart, r.BStart},
+	}
+}
+
+type aligner struct {
+	a, b  []bio.Base
+	s     bio.ScoringScheme
+	h     [][]int // h[i][j] is the best score of a[:i] against b[:j]
+	e     [][]int // best score ending with a[i-1] against a gap
+	f     [][]int // best score ending with b[j-1] against a gap
+	local bool
+	// alphabet of the result, that of a
+	alphabet *bio.Alphabet
+}
+
+func newAligner(a, b *bio.Sequence, s bio.ScoringScheme, local bool) *aligner {
+	rows, cols := len(a.Bases)+1, len(b.Bases)+1
+	return &aligner{
+		a:        a.Bases,
+		b:        b.Bases,
+		s:        s,
+		h:        bio.Slice2D(rows, cols, 0),
+		e:        bio.Slice2D(rows, cols, neg),
+		f:        bio.Slice2D(rows, cols, neg),
+		local:    local,
+		alphabet: a.Alphabet,
+	}
+}
+
+// NeedlemanWunsch returns an optimal global alignment of a and b.
+func NeedlemanWunsch(a, b *bio.Sequence, s bio.ScoringScheme) *Result {
+	al := newAligner(a, b, s, false)
+	al.fill()
+	return al.traceback(len(al.a), len(al.b))
+}
+
+// SmithWaterman returns an optimal local alignment of a and b.
+func SmithWaterman(a, b *bio.Sequence, s bio.ScoringScheme) *Result {
+	al := newAligner(a, b, s, true)
+	al.fill()
+	bi, bj := 0, 0
+	for i := range al.h {
+		for j := range al.h[i] {
+			if al.h[i][j] > al.h[bi][bj] {
+				bi, bj = i, j
+			}
+		}
+	}
+	return al.traceback(bi, bj)
+}
+
+func (al *aligner) fill() {
+	for i := range al.h {
+		for j := range al.h[i] {
+			al.cell(i, j)
+		}
+	}
+}
+
+func (al *aligner) cell(i, j int) {
+	if i == 0 && j == 0 {
+		return
+	}
+	g := al.s.Gaps
+	if i > 0 {
+		al.e[i][j] = bio.Max(al.h[i-1][j]+g.Open+g.Extend, al.e[i-1][j]+g.Extend)
+	}
+	if j > 0 {
+		al.f[i][j] = bio.Max(al.h[i][j-1]+g.Open+g.Extend, al.f[i][j-1]+g.Extend)
+	}
+	best := bio.Max(al.floor(), al.e[i][j], al.f[i][j])
+	if i > 0 && j > 0 {
+		best = bio.Max(best, al.h[i-1][j-1]+al.s.Pair(al.a[i-1], al.b[j-1]))
+	}
+	al.h[i][j] = best
+}
+
+// floor is the least score of a cell: zero for local alignments, where
+// an alignment may start at any cell.
+func (al *aligner) floor() int {
+	if al.local {
+		return 0
+	}
+	return neg
+}
+
+// traceback rebuilds the alignment ending at (i, j), following the table
+// (h, e or f) each step came from.
+func (al *aligner) traceback(i, j int) *Result {
+	r := &Result{
+		Score: al.s.Float(al.h[i][j]),
+	}
+	var ra, rb []bio.Base
+	table := 'h'
+	for i > 0 || j > 0 {
+		switch table {
+		case 'e':
+			// a gap in b, extended from e or opened after h
+			if al.e[i][j] != al.e[i-1][j]+al.s.Gaps.Extend {
+				table = 'h'
+			}
+			i--
+			ra, rb = append(ra, al.a[i]), append(rb, bio.X)
+			continue
+		case 'f':
+			if al.f[i][j] != al.f[i][j-1]+al.s.Gaps.Extend {
+				table = 'h'
+			}
+			j--
+			ra, rb = append(ra, bio.X), append(rb, al.b[j])
+			continue
+		}
+		h := al.h[i][j]
+		if al.local && h == 0 {
+			break
+		}
+		switch {
+		case i > 0 && j > 0 && h == al.h[i-1][j-1]+al.s.Pair(al.a[i-1], al.b[j-1]):
+			i, j = i-1, j-1
+			ra, rb = append(ra, al.a[i]), append(rb, al.b[j])
+		case h == al.e[i][j]:
+			table = 'e'
+		default:
+			table = 'f'
+		}
+	}
+	r.A = &bio.Sequence{Bases: reverse(ra), Alphabet: al.alphabet}
+	r.B = &bio.Sequence{Bases: reverse(rb), Alphabet: al.alphabet}
+	r.AStart, r.BStart = i, j
+	return r
+}
+
+func reverse(bases []bio.Base) []bio.Base {
+	if bases == nil {
+		bases = []bio.Base{}
+	}
+	for l, r := 0, len(bases)-1; l < r; l, r = l+1, r-1 {
+		bases[l], bases[r] = bases[r], bases[l]
+	}
+	return bases
+}
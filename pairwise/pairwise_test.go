@@ -0,0 +1,149 @@
+package pairwise
+
+import (
+	"math/rand"
+	"testing"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+func TestNeedlemanWunsch(t *testing.T) {
+	cases := []struct {
+		a, b   string
+		score  float64
+		ra, rb string
+	}{
+		{"ACGT", "ACGT", 12, "ACGT", "ACGT"},
+		{"ACGT", "AGT", 7.5, "ACGT", "A-GT"},
+		{"", "AC", -3, "--", "AC"},
+	}
+	for _, c := range cases {
+		r := NeedlemanWunsch(seq(t, c.a), seq(t, c.b), bio.DefaultScoring)
+		if r.Score != c.score || str(r.A) != c.ra || str(r.B) != c.rb {
+			t.Errorf("%v %v: %v %v %v", c.a, c.b, r.Score, str(r.A), str(r.B))
+		}
+	}
+}
+
+func TestSmithWaterman(t *testing.T) {
+	r := SmithWaterman(seq(t, "TTACGTAA"), seq(t, "GGACGTCC"), bio.DefaultScoring)
+	if r.Score != 12 || str(r.A) != "ACGT" || str(r.B) != "ACGT" || r.AStart != 2 || r.BStart != 2 {
+		t.Errorf("%v %v %v %v %v", r.Score, str(r.A), str(r.B), r.AStart, r.BStart)
+	}
+	r = SmithWaterman(seq(t, "AAAA"), seq(t, "CCCC"), bio.DefaultScoring)
+	if r.Score != 0 || len(r.A.Bases) != 0 {
+		t.Errorf("unrelated sequences: %v %v", r.Score, str(r.A))
+	}
+}
+
+// TestScoreMatchesAlignment rescores the returned alignments.
+func TestScoreMatchesAlignment(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 50; trial++ {
+		a, b := randomSeq(r, 1+r.Intn(20)), randomSeq(r, 1+r.Intn(20))
+		checkScore(t, a, b, bio.DefaultScoring)
+		if g, l := NeedlemanWunsch(a, b, bio.DefaultScoring).Score, SmithWaterman(a, b, bio.DefaultScoring).Score; l < g {
+			t.Fatalf("local score %v below global %v", l, g)
+		}
+	}
+	for _, mode := range []bio.Ambiguity{bio.ExpectedMatch, bio.BestMatch, bio.LiteralMatch} {
+		s := bio.DefaultScoring
+		s.Ambiguity = mode
+		checkScore(t, seq(t, "ACRT"), seq(t, "ACGT"), s)
+		checkScore(t, seq(t, "ACGNNACGT"), seq(t, "ACGTAACGT"), s)
+		for trial := 0; trial < 50; trial++ {
+			checkScore(t, randomIUPAC(r, 1+r.Intn(20)), randomIUPAC(r, 1+r.Intn(20)), s)
+		}
+	}
+}
+
+// checkScore checks that both aligners report the score of their alignment.
+func checkScore(t *testing.T, a, b *bio.Sequence, s bio.ScoringScheme) {
+	t.Helper()
+	for _, res := range []*Result{NeedlemanWunsch(a, b, s), SmithWaterman(a, b, s)} {
+		if len(res.A.Bases) != len(res.B.Bases) {
+			t.Fatalf("aligned lengths differ: %v %v", str(res.A), str(res.B))
+		}
+		if score := s.Float(bio.SPScore([]*bio.Sequence{res.A, res.B}, s)); score != res.Score {
+			t.Fatalf("%v %v: score %v, alignment scores %v", str(res.A), str(res.B), res.Score, score)
+		}
+	}
+}
+
+func TestAffine(t *testing.T) {
+	affine := bio.DefaultScoring
+	affine.Gaps = bio.GapCosts{Open: -10, Extend: -1}
+	r := NeedlemanWunsch(seq(t, "ACGTTTACGT"), seq(t, "ACGTACGT"), affine)
+	if r.Score != 18 || str(r.A) != "ACGTTTACGT" || str(r.B) != "ACG--TACGT" {
+		t.Errorf("one gap of two: %v %v %v", r.Score, str(r.A), str(r.B))
+	}
+
+	rnd := rand.New(rand.NewSource(2))
+	for trial := 0; trial < 50; trial++ {
+		a, b := randomSeq(rnd, 1+rnd.Intn(20)), randomSeq(rnd, 1+rnd.Intn(20))
+		for _, res := range []*Result{NeedlemanWunsch(a, b, affine), SmithWaterman(a, b, affine)} {
+			if s := affine.Float(bio.SPScore([]*bio.Sequence{res.A, res.B}, affine)); s != res.Score {
+				t.Fatalf("%v %v: score %v, alignment scores %v", str(res.A), str(res.B), res.Score, s)
+			}
+		}
+	}
+}
+
+func TestProtein(t *testing.T) {
+	a, err := bio.ParseSequence("HEAGAWGHEE", bio.Protein)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bio.ParseSequence("PAWHEAE", bio.Protein)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HEA scores 8+5+4, as much as AWGHE against AW-HE with an affine gap
+	r := SmithWaterman(a, b, bio.BLOSUM62.Scheme(bio.GapCosts{Open: -10, Extend: -1}))
+	if r.Score != 17 || str(r.A) != "HEA" || str(r.B) != "HEA" || r.B.Alphabet != bio.Protein {
+		t.Errorf("%v %v %v", r.Score, str(r.A), str(r.B))
+	}
+}
+
+func randomSeq(r *rand.Rand, n int) *bio.Sequence {
+	s := bio.NewSequence()
+	for i := 0; i < n; i++ {
+		s.Bases = append(s.Bases, bio.Base(r.Intn(4)))
+	}
+	return s
+}
+
+// randomIUPAC returns a random DNA sequence, a third of it ambiguity codes.
+func randomIUPAC(r *rand.Rand, n int) *bio.Sequence {
+	s := bio.NewSequence()
+	for i := 0; i < n; i++ {
+		b := bio.Base(r.Intn(4))
+		if r.Intn(3) == 0 {
+			b = bio.Purine + bio.Base(r.Intn(int(bio.AnyBase-bio.Purine)+1))
+		}
+		s.Bases = append(s.Bases, b)
+	}
+	return s
+}
+
+func str(s *bio.Sequence) string {
+	return s.String()
+}
+
+func TestResultAlignment(t *testing.T) {
+	r := SmithWaterman(seq(t, "TTACGTAA"), seq(t, "GGACGTCC"), bio.DefaultScoring)
+	a := r.Alignment("a", "b")
+	if a.Names[1] != "b" || a.Seqs[0] != r.A || a.Starts[1] != 2 {
+		t.Errorf("%v %v %v", a.Names, a.Seqs, a.Starts)
+	}
+}
+
+// seq reads a DNA sequence, ambiguity codes included, failing the test on other letters.
+func seq(t *testing.T, s string) *bio.Sequence {
+	t.Helper()
+	seq, err := bio.ParseSequence(s, bio.IUPAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seq
+}
@@ -0,0 +1,242 @@
+package rnafold
+
+import (
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// Folding of two interacting RNA strands.
+//
+// Cofold joins the strands with a linker of bases that never pair and folds
+// the result with the Zuker algorithm, allowing intramolecular pairs in both
+// strands as well as pairs between them. The loop holding the linker is an
+// exterior loop; joining the strands costs the intermolecular initiation
+// energy.
+//
+// Duplex only considers intermolecular pairs forming a single helix,
+// possibly interrupted by bulges and interior loops (as RNAduplex):
+//   D(i,j) = min( exterior(i,j) + init,
+//                 min_{k,l} interior(i,j,k,l) + D(k,l) )
+// for i in the first strand and j in the second, (k,l) nested inside.
+//
+// Positions in Options.Constraints and Options.Shape refer to the
+// concatenation of the two strands.
+
+// Interaction is the structure formed by two strands.
+type Interaction struct {
+	// Energy is the free energy in kcal/mol.
+	Energy float64
+	// Structure is over the concatenation of both strands.
+	Structure *Structure
+	// Cut is the length of the first strand.
+	Cut int
+}
+
+// DotBracket writes the structure with '&' between the strands,
+// ie. "((((..&..))))".
+func (x *Interaction) DotBracket() string {
+	db := x.Structure.DotBracket()
+	return db[:x.Cut] + "&" + db[x.Cut:]
+}
+
+// Cofold folds strands a and b together, returning the minimum free energy
+// structure of the pair. Pairs may form within and between the strands.
+func Cofold(a, b string, opts ...Options) (*Interaction, error) {
+	m, err := newCofoldModel(a, b, opts)
+	if err != nil {
+		return nil, err
+	}
+	z := newZuker(m)
+	z.fill()
+	if z.w5[z.n] >= inf {
+		return nil, errInfeasible
+	}
+	return m.interaction(z.w5[z.n], z.traceback()), nil
+}
+
+// Duplex returns the most stable helix that strands a and b can form
+// between them, ignoring intramolecular structure.
+func Duplex(a, b string, opts ...Options) (*Interaction, error) {
+	m, err := newCofoldModel(a, b, opts)
+	if err != nil {
+		return nil, err
+	}
+	d := &duplex{energyModel: m, e: bio.Slice2D(m.linkStart, len(m.seq), inf)}
+	d.fill()
+	best, bi, bj := inf, 0, 0
+	for i := 0; i < m.linkStart; i++ {
+		for j := m.linkEnd; j < len(m.seq); j++ {
+			if d.e[i][j] >= inf || !m.cons.canSkip(0, i-1) || !m.cons.canSkip(j+1, len(m.seq)-1) {
+				continue
+			}
+			if e := d.e[i][j] + m.exterior(i, j); e < best {
+				best, bi, bj = e, i, j
+			}
+		}
+	}
+	if best >= inf {
+		return nil, fmt.Errorf("rnafold: the strands cannot form a duplex")
+	}
+	return m.interaction(best, d.traceback(bi, bj)), nil
+}
+
+// newCofoldModel returns the energy model of a and b joined by a linker
+// long enough for any pair between the strands.
+func newCofoldModel(a, b string, opts []Options) (*energyModel, error) {
+	ea, err := encode(a)
+	if err != nil {
+		return nil, err
+	}
+	eb, err := encode(b)
+	if err != nil {
+		return nil, err
+	}
+	o, err := optionsOf(opts)
+	if err != nil {
+		return nil, err
+	}
+	link := bio.Max(o.MinLoop, minHairpin)
+	enc := append([]int(nil), ea...)
+	for k := 0; k < link; k++ {
+		enc = append(enc, nucLinker)
+	}
+	enc = append(enc, eb...)
+	if o.Shape != nil {
+		if len(o.Shape.Reactivity) != len(ea)+len(eb) {
+			return nil, fmt.Errorf("rnafold: %d reactivities for strands of length %d",
+				len(o.Shape.Reactivity), len(ea)+len(eb))
+		}
+		o.Shape = o.Shape.insert(len(ea), link)
+	}
+	o.Constraints = o.Constraints.insert(len(ea), link)
+	m, err := newEncodedModel(enc, o)
+	if err != nil {
+		return nil, err
+	}
+	m.linkStart, m.linkEnd = len(ea), len(ea)+link
+	return m, nil
+}
+
+// interaction removes the linker from pairs.
+func (m *energyModel) interaction(e int, pairs []Pair) *Interaction {
+	link := m.linkEnd - m.linkStart
+	for k, p := range pairs {
+		if p.I >= m.linkEnd {
+			p.I -= link
+		}
+		if p.J >= m.linkEnd {
+			p.J -= link
+		}
+		pairs[k] = p
+	}
+	return &Interaction{
+		Energy:    float64(e) / 100,
+		Structure: NewStructure(len(m.seq)-link, pairs),
+		Cut:       m.linkStart,
+	}
+}
+
+// insert returns the constraints with k positions inserted at at.
+func (c *Constraints) insert(at, k int) *Constraints {
+	if c == nil {
+		return nil
+	}
+	shift := func(i int) int {
+		if i >= at {
+			return i + k
+		}
+		return i
+	}
+	shiftPairs := func(pairs []Pair) []Pair {
+		s := make([]Pair, len(pairs))
+		for n, p := range pairs {
+			s[n] = Pair{I: shift(p.I), J: shift(p.J)}
+		}
+		return s
+	}
+	s := &Constraints{
+		Forced:    shiftPairs(c.Forced),
+		Forbidden: shiftPairs(c.Forbidden),
+	}
+	for _, i := range c.Unpaired {
+		s.Unpaired = append(s.Unpaired, shift(i))
+	}
+	return s
+}
+
+// insert returns the data with k missing reactivities inserted at at.
+func (s *Shape) insert(at, k int) *Shape {
+	r := append([]float64(nil), s.Reactivity[:at]...)
+	for n := 0; n < k; n++ {
+		r = append(r, -1)
+	}
+	r = append(r, s.Reactivity[at:]...)
+	return &Shape{Reactivity: r, Slope: s.Slope, Intercept: s.Intercept}
+}
+
+type duplex struct {
+	*energyModel
+	e [][]int // e[i][j] is D(i,j)
+}
+
+func (d *duplex) fill() {
+	for i := d.linkStart - 1; i >= 0; i-- {
+		for j := d.linkEnd; j < len(d.seq); j++ {
+			d.e[i][j] = d.cell(i, j)
+		}
+	}
+}
+
+func (d *duplex) cell(i, j int) int {
+	if !d.canPair(i, j) {
+		return inf
+	}
+	best := inf
+	if d.cons.canSkip(i+1, d.linkStart-1) && d.cons.canSkip(d.linkEnd, j-1) {
+		best = d.exterior(i, j) + d.p.duplexInit
+	}
+	d.innerPairs(i, j, func(k, l int) {
+		if e := d.interior(i, j, k, l); e < inf {
+			best = bio.Min(best, e+d.e[k][l])
+		}
+	})
+	return best
+}
+
+// innerPairs calls fn for every pair (k, l) of the duplex that can close a
+// stack, bulge or interior loop with (i, j).
+func (d *duplex) innerPairs(i, j int, fn func(k, l int)) {
+	for k := i + 1; k < d.linkStart && k-i-1 <= maxLoop; k++ {
+		for l := j - 1; l >= d.linkEnd && k-i-1+j-l-1 <= maxLoop; l-- {
+			if d.e[k][l] < inf {
+				fn(k, l)
+			}
+		}
+	}
+}
+
+func (d *duplex) traceback(i, j int) []Pair {
+	pairs := []Pair{{I: i, J: j}}
+	for {
+		e := d.e[i][j]
+		found := false
+		d.innerPairs(i, j, func(k, l int) {
+			if found {
+				return
+			}
+			if loop := d.interior(i, j, k, l); loop < inf && e == loop+d.e[k][l] {
+				i, j, found = k, l, true
+			}
+		})
+		if !found {
+			return pairs
+		}
+		pairs = append(pairs, Pair{I: i, J: j})
+	}
+}
+
+// String returns the structure and energy, ie. "((((..&..)))) (-5.80)".
+func (x *Interaction) String() string {
+	return fmt.Sprintf("%s (%.2f)", x.DotBracket(), x.Energy)
+}
@@ -0,0 +1,71 @@
+package rnafold
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCofold(t *testing.T) {
+	// the GGGGAAACCCC hairpin cut in its loop: the same three stacks,
+	// the hairpin loop replaced by the intermolecular initiation
+	x, err := Cofold("GGGGAA", "ACCCC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(x.Energy-(-9.9+4.1)) > 1e-9 || x.DotBracket() != "((((..&.))))" {
+		t.Fatalf("Cofold %v", x)
+	}
+	// strands that cannot pair with each other fold alone
+	x, err = Cofold("GGGGAAACCCC", "AAAAA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(x.Energy-(-4.5)) > 1e-9 || x.DotBracket() != "((((...))))&....." {
+		t.Fatalf("Cofold %v", x)
+	}
+}
+
+func TestDuplex(t *testing.T) {
+	x, err := Duplex("GGGGGG", "CCCCCC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(x.Energy-(5*-3.3+4.1)) > 1e-9 || x.DotBracket() != "((((((&))))))" {
+		t.Fatalf("Duplex %v", x)
+	}
+	// the hairpin's own pairs are out of reach of a duplex
+	x, err = Duplex("AAGGGGAAACCCCAA", "GGGG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range x.Structure.Pairs {
+		if p.I >= x.Cut || p.J < x.Cut {
+			t.Fatalf("intramolecular pair %v in %v", p, x)
+		}
+	}
+	if _, err := Duplex("AAAA", "AAAA"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+// TestCofoldBounds checks that cofolding never does worse than folding the
+// strands apart or forming their best duplex.
+func TestCofoldBounds(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	for trial := 0; trial < 20; trial++ {
+		a, b := randomRNA(r, 5+r.Intn(20)), randomRNA(r, 5+r.Intn(20))
+		x, err := Cofold(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ea, _, _ := MFE(a)
+		eb, _, _ := MFE(b)
+		if x.Energy > ea+eb+1e-9 {
+			t.Fatalf("%v&%v: cofold %v above monomers %v", a, b, x, ea+eb)
+		}
+		if d, err := Duplex(a, b); err == nil && x.Energy > d.Energy+1e-9 {
+			t.Fatalf("%v&%v: cofold %v above duplex %v", a, b, x, d)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package rnafold
+
+import (
+	"fmt"
+	"math"
+)
+
+// Comparison of secondary structures, ie. a prediction against a reference.
+
+// bracket pairs recognised in dot-bracket and WUSS style notation
+var brackets = []struct{ open, close byte }{
+	{'(', ')'}, {'[', ']'}, {'{', '}'}, {'<', '>'},
+}
+
+// ParseDotBracket parses a structure in dot-bracket notation. Pairs may use
+// (), [], {} and <> as well as matching upper and lower case letters (Aa)
+// for pseudoknots; any other character is an unpaired base.
+func ParseDotBracket(db string) (*Structure, error) {
+	stacks := map[byte][]int{}
+	var pairs []Pair
+	for i := 0; i < len(db); i++ {
+		c := db[i]
+		open, isOpen, isClose := bracketOf(c)
+		switch {
+		case isOpen:
+			stacks[open] = append(stacks[open], i)
+		case isClose:
+			s := stacks[open]
+			if len(s) == 0 {
+				return nil, fmt.Errorf("rnafold: unmatched %q at position %d", c, i)
+			}
+			pairs = append(pairs, Pair{I: s[len(s)-1], J: i})
+			stacks[open] = s[:len(s)-1]
+		}
+	}
+	for open, s := range stacks {
+		if len(s) > 0 {
+			return nil, fmt.Errorf("rnafold: unmatched %q at position %d", open, s[len(s)-1])
+		}
+	}
+	return NewStructure(len(db), pairs), nil
+}
+
+// bracketOf returns the opening symbol of c's bracket type and whether c
+// opens or closes a pair.
+func bracketOf(c byte) (open byte, isOpen, isClose bool) {
+	for _, b := range brackets {
+		switch c {
+		case b.open:
+			return b.open, true, false
+		case b.close:
+			return b.open, false, true
+		}
+	}
+	switch {
+	case c >= 'A' && c <= 'Z':
+		return c, true, false
+	case c >= 'a' && c <= 'z':
+		return c - 'a' + 'A', false, true
+	}
+	return 0, false, false
+}
+
+// Metrics compares a predicted structure with a reference.
+type Metrics struct {
+	TP, FP, FN, TN int // confusion counts over all possible pairs
+	Sensitivity    float64
+	PPV            float64 // positive predictive value
+	F1             float64
+	MCC            float64 // Matthews correlation coefficient
+}
+
+// Compare scores pred against ref. With slip, a pair (i, j) also matches
+// (i±1, j) and (i, j±1) in the other structure. Sensitivity (PPV) is one
+// when ref (pred) has no pairs.
+func Compare(ref, pred *Structure, slip bool) (Metrics, error) {
+	var m Metrics
+	if ref.Length != pred.Length {
+		return m, lengthError(ref, pred)
+	}
+	refFound := countMatched(ref, pred, slip)
+	predFound := countMatched(pred, ref, slip)
+	m.TP = predFound
+	m.FP = len(pred.Pairs) - predFound
+	m.FN = len(ref.Pairs) - refFound
+	m.TN = ref.Length*(ref.Length-1)/2 - m.TP - m.FP - m.FN
+	m.Sensitivity, m.PPV = 1, 1
+	if len(ref.Pairs) > 0 {
+		m.Sensitivity = float64(refFound) / float64(len(ref.Pairs))
+	}
+	if len(pred.Pairs) > 0 {
+		m.PPV = float64(predFound) / float64(len(pred.Pairs))
+	}
+	if m.Sensitivity+m.PPV > 0 {
+		m.F1 = 2 * m.Sensitivity * m.PPV / (m.Sensitivity + m.PPV)
+	}
+	tp, fp, fn, tn := float64(m.TP), float64(m.FP), float64(m.FN), float64(m.TN)
+	if d := (tp + fp) * (tp + fn) * (tn + fp) * (tn + fn); d > 0 {
+		m.MCC = (tp*tn - fp*fn) / math.Sqrt(d)
+	} else if m.FP == 0 && m.FN == 0 {
+		m.MCC = 1
+	}
+	return m, nil
+}
+
+// countMatched returns the number of pairs of a found in b.
+func countMatched(a, b *Structure, slip bool) (count int) {
+	set := pairSet(b)
+	for _, p := range a.Pairs {
+		if set[p] || slip && (set[Pair{p.I - 1, p.J}] || set[Pair{p.I + 1, p.J}] ||
+			set[Pair{p.I, p.J - 1}] || set[Pair{p.I, p.J + 1}]) {
+			count++
+		}
+	}
+	return
+}
+
+func pairSet(s *Structure) map[Pair]bool {
+	set := make(map[Pair]bool, len(s.Pairs))
+	for _, p := range s.Pairs {
+		set[p] = true
+	}
+	return set
+}
+
+// BasePairDistance returns the number of pairs in exactly one of a and b.
+func BasePairDistance(a, b *Structure) (int, error) {
+	if a.Length != b.Length {
+		return 0, lengthError(a, b)
+	}
+	shared := countMatched(a, b, false)
+	return len(a.Pairs) + len(b.Pairs) - 2*shared, nil
+}
+
+// MountainDistance returns the L1 distance between the mountain
+// representations of a and b, where the height after base k is the number
+// of pairs (i, j) with i <= k < j.
+func MountainDistance(a, b *Structure) (int, error) {
+	if a.Length != b.Length {
+		return 0, lengthError(a, b)
+	}
+	ma, mb := mountain(a), mountain(b)
+	d := 0
+	for k := range ma {
+		if ma[k] > mb[k] {
+			d += ma[k] - mb[k]
+		} else {
+			d += mb[k] - ma[k]
+		}
+	}
+	return d, nil
+}
+
+func mountain(s *Structure) []int {
+	delta := make([]int, s.Length+1)
+	for _, p := range s.Pairs {
+		delta[p.I]++
+		delta[p.J]--
+	}
+	heights := make([]int, s.Length)
+	h := 0
+	for k := range heights {
+		h += delta[k]
+		heights[k] = h
+	}
+	return heights
+}
+
+func lengthError(a, b *Structure) error {
+	return fmt.Errorf("rnafold: structures of different lengths %d and %d", a.Length, b.Length)
+}
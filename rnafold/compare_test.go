@@ -0,0 +1,71 @@
+package rnafold
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseDotBracket(t *testing.T) {
+	s, err := ParseDotBracket("((..[[..))..]]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Pair{{0, 9}, {1, 8}, {4, 13}, {5, 12}}
+	if len(s.Pairs) != len(want) {
+		t.Fatalf("parsed %v, want %v", s.Pairs, want)
+	}
+	for k, p := range want {
+		if s.Pairs[k] != p {
+			t.Fatalf("parsed %v, want %v", s.Pairs, want)
+		}
+	}
+	for _, bad := range []string{"(()", "())", "(]"} {
+		if _, err := ParseDotBracket(bad); err == nil {
+			t.Fatalf("expected an error for %v", bad)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ref, _ := ParseDotBracket("((((....))))")
+	pred, _ := ParseDotBracket(".(((....))).")
+	m, err := Compare(ref, pred, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(m)
+	if m.TP != 3 || m.FP != 0 || m.FN != 1 || m.PPV != 1 || m.Sensitivity != 0.75 {
+		t.Fatalf("unexpected metrics %+v", m)
+	}
+	if math.Abs(m.F1-6.0/7) > 1e-9 {
+		t.Fatalf("F1 %v, want %v", m.F1, 6.0/7)
+	}
+	shifted, _ := ParseDotBracket("((((...)))).")
+	exact, _ := Compare(ref, shifted, false)
+	slipped, _ := Compare(ref, shifted, true)
+	if exact.TP != 0 || slipped.TP != 4 {
+		t.Fatalf("slippage TP %v exact, %v slipped", exact.TP, slipped.TP)
+	}
+	if m, _ := Compare(ref, ref, false); m.MCC != 1 || m.F1 != 1 {
+		t.Fatalf("self comparison %+v", m)
+	}
+	if _, err := Compare(ref, NewStructure(3, nil), false); err == nil {
+		t.Fatal("expected an error for differing lengths")
+	}
+}
+
+func TestDistances(t *testing.T) {
+	a, _ := ParseDotBracket("((..))..")
+	b, _ := ParseDotBracket("(....)..")
+	c, _ := ParseDotBracket("......()")
+	if d, _ := BasePairDistance(a, b); d != 1 {
+		t.Fatalf("base pair distance %v, want 1", d)
+	}
+	if d, _ := BasePairDistance(a, c); d != 3 {
+		t.Fatalf("base pair distance %v, want 3", d)
+	}
+	// mountains: a 1 2 2 2 1 0 0 0, b 1 1 1 1 1 0 0 0
+	if d, _ := MountainDistance(a, b); d != 3 {
+		t.Fatalf("mountain distance %v, want 3", d)
+	}
+}
@@ -0,0 +1,144 @@
+package rnafold
+
+import "fmt"
+
+// Constraints restrict the structures a folder may return.
+type Constraints struct {
+	Unpaired  []int  // positions that must stay unpaired
+	Forced    []Pair // pairs that must form
+	Forbidden []Pair // pairs that must not form
+	// Length is the length of the sequence the constraints are for, set by
+	// ParseConstraints. Zero means any length.
+	Length int
+}
+
+// ParseConstraints reads a constraint string the length of the sequence:
+//
+//	.  no constraint
+//	x  unpaired
+//	() forced pair
+//
+// Folding with the constraints fails unless the sequence has the length
+// of s.
+func ParseConstraints(s string) (*Constraints, error) {
+	c := &Constraints{Length: len(s)}
+	var open []int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '.':
+		case 'x':
+			c.Unpaired = append(c.Unpaired, i)
+		case '(':
+			open = append(open, i)
+		case ')':
+			if len(open) == 0 {
+				return nil, fmt.Errorf("rnafold: unmatched ')' at position %d", i)
+			}
+			c.Forced = append(c.Forced, Pair{I: open[len(open)-1], J: i})
+			open = open[:len(open)-1]
+		default:
+			return nil, fmt.Errorf("rnafold: invalid constraint %q at position %d", s[i], i)
+		}
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("rnafold: unmatched '(' at position %d", open[len(open)-1])
+	}
+	return c, nil
+}
+
+// constraint is Constraints indexed by position for the folders.
+// A nil *constraint allows everything.
+type constraint struct {
+	partner   []int // forced partner, or -1
+	unpaired  []bool
+	forbidden map[Pair]bool
+	forced    []int // forced[k] is the number of forced positions before k
+}
+
+// impossible scores an interval no Nussinov structure can fill
+// without breaking a constraint.
+const impossible = -inf
+
+var errInfeasible = fmt.Errorf("rnafold: constraints cannot be satisfied")
+
+func newConstraint(c *Constraints, length int) (*constraint, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if c.Length != 0 && c.Length != length {
+		return nil, fmt.Errorf("rnafold: constraints of length %d for a sequence of length %d",
+			c.Length, length)
+	}
+	k := &constraint{
+		partner:   make([]int, length),
+		unpaired:  make([]bool, length),
+		forbidden: map[Pair]bool{},
+		forced:    make([]int, length+1),
+	}
+	for i := range k.partner {
+		k.partner[i] = -1
+	}
+	inRange := func(i int) error {
+		if i < 0 || i >= length {
+			return fmt.Errorf("rnafold: constraint position %d out of range", i)
+		}
+		return nil
+	}
+	for _, i := range c.Unpaired {
+		if err := inRange(i); err != nil {
+			return nil, err
+		}
+		k.unpaired[i] = true
+	}
+	for _, p := range c.Forced {
+		if err := inRange(p.I); err != nil {
+			return nil, err
+		}
+		if err := inRange(p.J); err != nil {
+			return nil, err
+		}
+		if p.I >= p.J || k.partner[p.I] >= 0 || k.partner[p.J] >= 0 ||
+			k.unpaired[p.I] || k.unpaired[p.J] {
+			return nil, fmt.Errorf("rnafold: conflicting constraint on pair (%d, %d)", p.I, p.J)
+		}
+		k.partner[p.I], k.partner[p.J] = p.J, p.I
+	}
+	for _, p := range c.Forbidden {
+		if err := inRange(p.I); err != nil {
+			return nil, err
+		}
+		if err := inRange(p.J); err != nil {
+			return nil, err
+		}
+		if p.I > p.J {
+			p.I, p.J = p.J, p.I
+		}
+		k.forbidden[p] = true
+	}
+	for i, p := range k.partner {
+		k.forced[i+1] = k.forced[i]
+		if p >= 0 {
+			k.forced[i+1]++
+		}
+	}
+	return k, nil
+}
+
+// allowPair reports whether i may pair with j.
+func (k *constraint) allowPair(i, j int) bool {
+	if k == nil {
+		return true
+	}
+	if k.unpaired[i] || k.unpaired[j] || k.forbidden[Pair{I: i, J: j}] {
+		return false
+	}
+	return (k.partner[i] < 0 || k.partner[i] == j) && (k.partner[j] < 0 || k.partner[j] == i)
+}
+
+// canSkip reports whether every base in [i, j] may stay unpaired.
+func (k *constraint) canSkip(i, j int) bool {
+	if k == nil || j < i {
+		return true
+	}
+	return k.forced[j+1] == k.forced[i]
+}
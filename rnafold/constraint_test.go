@@ -0,0 +1,171 @@
+package rnafold
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestParseConstraints(t *testing.T) {
+	c, err := ParseConstraints("x(.(..)).x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Unpaired) != 2 || c.Unpaired[0] != 0 || c.Unpaired[1] != 9 {
+		t.Fatalf("unpaired %v", c.Unpaired)
+	}
+	if len(c.Forced) != 2 || c.Forced[0] != (Pair{3, 6}) || c.Forced[1] != (Pair{1, 7}) {
+		t.Fatalf("forced %v", c.Forced)
+	}
+	for _, bad := range []string{"(()", "())", "(.a)"} {
+		if _, err := ParseConstraints(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestConstrainedFold(t *testing.T) {
+	seq := "GGGGAAACCCCAAAGGGAAACCC"
+	c, _ := ParseConstraints("x.............(.....)..")
+	_, s, err := Fold(seq, Options{Constraints: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !satisfies(s, c) {
+		t.Fatalf("Fold %v breaks the constraints", s.DotBracket())
+	}
+	_, s, err = MFE(seq, Options{Constraints: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !satisfies(s, c) {
+		t.Fatalf("MFE %v breaks the constraints", s.DotBracket())
+	}
+
+	c = &Constraints{Forced: []Pair{{0, 1}}}
+	if _, err := FoldScore("GC", Options{Constraints: c}); err != errInfeasible {
+		t.Fatalf("FoldScore: expected errInfeasible, got %v", err)
+	}
+	c = &Constraints{Forced: []Pair{{0, 3}}}
+	if _, _, err := MFE("GAAC", Options{Constraints: c}); err != errInfeasible {
+		t.Fatalf("MFE: expected errInfeasible, got %v", err)
+	}
+	// (1, 12) cannot pair, so (0, 13) only looks feasible through the
+	// stacked helix inside the interior loop holding 1 and 12.
+	c = &Constraints{Forced: []Pair{{0, 13}, {1, 12}}}
+	if _, _, err := MFE("GAGGGAAAACCCAC", Options{Constraints: c}); err != errInfeasible {
+		t.Fatalf("MFE: expected errInfeasible, got %v", err)
+	}
+	c = &Constraints{Forbidden: []Pair{{4, 0}}}
+	if _, s, err := Fold("GAAAC", Options{Constraints: c}); err != nil || s.DotBracket() != "....." {
+		t.Fatalf("Fold with (0, 4) forbidden: %v, %v", s, err)
+	}
+	c = &Constraints{Forbidden: []Pair{{0, 5}}}
+	if _, err := FoldScore("GAAAC", Options{Constraints: c}); err == nil {
+		t.Fatal("expected an out of range error")
+	}
+	for _, db := range []string{"(...)", "(.......)"} {
+		c, _ = ParseConstraints(db)
+		if _, _, err := MFE("GGAAACC", Options{Constraints: c}); err == nil {
+			t.Errorf("%v: expected a length error", db)
+		}
+	}
+	c = &Constraints{Unpaired: []int{5}}
+	if _, err := FoldScore("GAC", Options{Constraints: c}); err == nil {
+		t.Fatal("expected an out of range error")
+	}
+}
+
+// TestConstraintsExhaustive checks every folder against the best of the
+// structures satisfying random constraints.
+func TestConstraintsExhaustive(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	for trial := 0; trial < 30; trial++ {
+		seq := randomRNA(r, 10+r.Intn(5))
+		structs := allStructures(seq, minHairpin)
+		c := &Constraints{}
+		if s := structs[r.Intn(len(structs))]; len(s.Pairs) > 0 {
+			c.Forced = append(c.Forced, s.Pairs[r.Intn(len(s.Pairs))])
+		}
+		for _, p := range structs[r.Intn(len(structs))].Pairs {
+			if len(c.Forced) == 0 || p != c.Forced[0] {
+				c.Forbidden = append(c.Forbidden, p)
+				break
+			}
+		}
+		if u := r.Intn(len(seq)); len(c.Forced) == 0 || u != c.Forced[0].I && u != c.Forced[0].J {
+			c.Unpaired = append(c.Unpaired, u)
+		}
+		opts := Options{MinLoop: minHairpin, Constraints: c}
+
+		bestPairs, bestEnergy, z := -1, math.Inf(1), 0.0
+		for _, s := range structs {
+			if !satisfies(s, c) {
+				continue
+			}
+			if len(s.Pairs) > bestPairs {
+				bestPairs = len(s.Pairs)
+			}
+			e, _ := EvalEnergy(seq, s)
+			bestEnergy = math.Min(bestEnergy, e)
+			z += math.Exp(-e * 100 / kT)
+		}
+		if bestPairs < 0 {
+			continue
+		}
+
+		for name, fold := range map[string]func(string, ...Options) (int, error){
+			"FoldScore": FoldScore, "IterativeFoldScore": IterativeFoldScore,
+		} {
+			if score, err := fold(seq, opts); err != nil || score != bestPairs {
+				t.Fatalf("%v %+v: %v %v (%v), exhaustive %v", seq, *c, name, score, err, bestPairs)
+			}
+		}
+		score, s, err := Fold(seq, opts)
+		if err != nil || score != bestPairs || len(s.Pairs) != score || !satisfies(s, c) {
+			t.Fatalf("%v %+v: Fold %v %v (%v)", seq, *c, score, s, err)
+		}
+		e, s, err := MFE(seq, opts)
+		if err != nil || math.Abs(e-bestEnergy) > 1e-9 || !satisfies(s, c) {
+			t.Fatalf("%v %+v: MFE %v %v (%v), exhaustive %v", seq, *c, e, s, err, bestEnergy)
+		}
+		ens, err := PartitionFunction(seq, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g := -kT * math.Log(z) / 100; math.Abs(g-ens.FreeEnergy) > 1e-6 {
+			t.Fatalf("%v %+v: free energy %v, exhaustive %v", seq, *c, ens.FreeEnergy, g)
+		}
+		for _, s := range ens.Sample(20, r) {
+			if !satisfies(s, c) {
+				t.Fatalf("%v %+v: sampled %v", seq, *c, s.DotBracket())
+			}
+		}
+	}
+}
+
+func satisfies(s *Structure, c *Constraints) bool {
+	pt := make([]int, s.Length)
+	for i := range pt {
+		pt[i] = -1
+	}
+	for _, p := range s.Pairs {
+		pt[p.I], pt[p.J] = p.J, p.I
+	}
+	for _, i := range c.Unpaired {
+		if pt[i] >= 0 {
+			return false
+		}
+	}
+	for _, p := range c.Forced {
+		if pt[p.I] != p.J {
+			return false
+		}
+	}
+	for _, p := range c.Forbidden {
+		if pt[p.I] == p.J {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package rnafold
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Inverse folding: finding a sequence that folds into a target structure.
+// Input:
+// - A target secondary structure in dot-bracket notation (no pseudoknots)
+// Output:
+// - A sequence whose predicted structure is the target
+//
+// An adaptive walk starts from a seed or a random sequence with
+// complementary bases at the target's pairs. Each step mutates a base that
+// the current prediction gets wrong (or both bases of a target pair, keeping
+// them complementary) and keeps the mutation unless it moves the prediction
+// further from the target, by base pair distance.
+
+// DesignOptions configures Design. The zero value designs with the energy
+// model from a random start.
+type DesignOptions struct {
+	// Seed is the starting sequence, random if empty.
+	Seed string
+	// Rand is the source of randomness, nil for a fixed seed.
+	Rand *rand.Rand
+	// MaxSteps bounds the number of mutations tried. Zero means 1000.
+	MaxSteps int
+	// Nussinov designs for Fold instead of MFE.
+	Nussinov bool
+	// Fold are the options passed to the folder.
+	Fold Options
+}
+
+// complementary pairs used to fill in target pairs
+var designPairs = [...]string{"GC", "CG", "AU", "UA"}
+
+const designBases = "ACGU"
+
+// Design searches for a sequence folding into target, returning the best
+// sequence found and the base pair distance between its prediction and the
+// target, zero if the design succeeded.
+func Design(target string, o DesignOptions) (seq string, dist int, err error) {
+	t, err := ParseDotBracket(target)
+	if err != nil {
+		return "", 0, err
+	}
+	r := o.Rand
+	if r == nil {
+		r = rand.New(rand.NewSource(1))
+	}
+	steps := o.MaxSteps
+	if steps == 0 {
+		steps = 1000
+	}
+	// ParseDotBracket accepts pseudoknots ([], {}, <> and letters), which
+	// pairTable rejects as crossing pairs
+	pt, err := pairTable(t)
+	if err != nil {
+		return "", 0, err
+	}
+
+	cur := make([]byte, t.Length)
+	if o.Seed != "" {
+		seed, err := Normalize(o.Seed)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(seed) != t.Length {
+			return "", 0, fmt.Errorf("rnafold: seed length %d differs from target length %d",
+				len(seed), t.Length)
+		}
+		copy(cur, seed)
+	} else {
+		for i := range cur {
+			if j := pt[i]; j < 0 {
+				cur[i] = designBases[r.Intn(len(designBases))]
+			} else if j > i {
+				pair := designPairs[r.Intn(len(designPairs))]
+				cur[i], cur[j] = pair[0], pair[1]
+			}
+		}
+	}
+
+	fold := func(seq []byte) (*Structure, int, error) {
+		var s *Structure
+		var err error
+		if o.Nussinov {
+			_, s, err = Fold(string(seq), o.Fold)
+		} else {
+			_, s, err = MFE(string(seq), o.Fold)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		d, err := BasePairDistance(t, s)
+		return s, d, err
+	}
+	pred, dist, err := fold(cur)
+	if err != nil {
+		return "", 0, err
+	}
+	next := make([]byte, len(cur))
+	for step := 0; step < steps && dist > 0; step++ {
+		predPT, err := pairTable(pred)
+		if err != nil {
+			return "", 0, err
+		}
+		wrong := misfolded(pt, predPT)
+		i := wrong[r.Intn(len(wrong))]
+		copy(next, cur)
+		if j := pt[i]; j < 0 {
+			next[i] = designBases[r.Intn(len(designBases))]
+		} else {
+			pair := designPairs[r.Intn(len(designPairs))]
+			next[i], next[j] = pair[0], pair[1]
+			if j < i {
+				next[i], next[j] = pair[1], pair[0]
+			}
+		}
+		s, d, err := fold(next)
+		if err != nil {
+			return "", 0, err
+		}
+		if d <= dist {
+			cur, next = next, cur
+			pred, dist = s, d
+		}
+	}
+	return string(cur), dist, nil
+}
+
+// misfolded returns the positions whose partner differs between the target
+// and the prediction.
+func misfolded(target, pred []int) []int {
+	var wrong []int
+	for i := range target {
+		if target[i] != pred[i] {
+			wrong = append(wrong, i)
+		}
+	}
+	return wrong
+}
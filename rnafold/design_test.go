@@ -0,0 +1,46 @@
+package rnafold
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDesign(t *testing.T) {
+	for _, target := range []string{
+		"((((...))))",
+		"((((....))))...((((....))))",
+		"(((((..((((...))))..((((...))))..)))))",
+	} {
+		seq, dist, err := Design(target, DesignOptions{Rand: rand.New(rand.NewSource(17))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, s, _ := MFE(seq)
+		if dist != 0 || s.DotBracket() != target {
+			t.Fatalf("%v: designed %v folding into %v (distance %v)", target, seq, s.DotBracket(), dist)
+		}
+	}
+
+	target := "(((....)))..(((....)))"
+	seq, dist, err := Design(target, DesignOptions{Nussinov: true, Fold: Options{MinLoop: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, s, _ := Fold(seq, Options{MinLoop: 3})
+	if dist != 0 || s.DotBracket() != target {
+		t.Fatalf("%v: designed %v folding into %v (distance %v)", target, seq, s.DotBracket(), dist)
+	}
+}
+
+func TestDesignSeed(t *testing.T) {
+	seq, dist, err := Design("((((...))))", DesignOptions{Seed: "gggGAAACCCC", MaxSteps: 1})
+	if err != nil || dist != 0 || seq != "GGGGAAACCCC" {
+		t.Fatalf("seeded design %v %v %v", seq, dist, err)
+	}
+	if _, _, err := Design("((((...))))", DesignOptions{Seed: "GGGG"}); err == nil {
+		t.Fatal("expected a length error")
+	}
+	if _, _, err := Design("((..[[..))..]]", DesignOptions{}); err == nil {
+		t.Fatal("expected a pseudoknot error")
+	}
+}
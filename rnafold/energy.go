@@ -0,0 +1,593 @@
+package rnafold
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Nearest-neighbor free energy model.
+// Energies are held as integers in dcal/mol (1/100 kcal/mol) so that the
+// dynamic programming is exact; results are converted to kcal/mol.
+
+const (
+	inf        = 10000000 // energy of an impossible structure
+	maxLoop    = 30       // largest interior loop / bulge considered
+	minHairpin = 3        // smallest hairpin loop the energy model allows
+)
+
+// nucleotides in encoded form
+const (
+	nucA = iota
+	nucC
+	nucG
+	nucU
+	nucLinker // joins two strands, never pairs
+)
+
+// pair types, indexing the stacking table
+const (
+	pairAU = iota
+	pairCG
+	pairGC
+	pairUA
+	pairGU
+	pairUG
+	numPairs
+)
+
+var pairNames = [numPairs]string{"AU", "CG", "GC", "UA", "GU", "UG"}
+
+// EnergyParams holds a set of nearest-neighbor parameters at 37°C.
+type EnergyParams struct {
+	stack      [numPairs][numPairs]int // stack[outer][inner], inner pair read 5' to 3'
+	hairpin    [maxLoop + 1]int
+	bulge      [maxLoop + 1]int
+	interior   [maxLoop + 1]int
+	terminalAU int // per AU/GU helix end
+	ninio      int // interior loop asymmetry, per unpaired base of difference
+	ninioMax   int
+	mlClosing  int     // multiloop initiation (a)
+	mlUnpaired int     // per unpaired base in a multiloop (b)
+	mlBranch   int     // per branch of a multiloop (c)
+	lxc        float64 // extrapolation factor for loops larger than maxLoop
+	duplexInit int     // intermolecular initiation of two strands
+}
+
+// defaultParams is the default set, parsed once as every fold uses it.
+var defaultParams = mustLoadEnergyParams(turner2004)
+
+func mustLoadEnergyParams(s string) *EnergyParams {
+	p, err := LoadEnergyParams(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// DefaultEnergyParams returns a simplified Turner 2004 parameter set.
+func DefaultEnergyParams() *EnergyParams {
+	p := *defaultParams
+	return &p
+}
+
+// LoadEnergyParams reads parameters in the format of the default set
+// (see turner2004). Values are in kcal/mol. Sections are:
+//
+//	[stack]     a header of inner pairs then one row per outer pair
+//	[hairpin]   "size energy" lines for hairpin loops of 3 to 30 bases
+//	[bulge]     "size energy" lines for bulges of 1 to 30 bases
+//	[interior]  "size energy" lines for interior loops of 2 to 30 bases
+//	[misc]      "name value" lines
+//
+// Entries that are not given are impossible (infinite energy).
+func LoadEnergyParams(r io.Reader) (*EnergyParams, error) {
+	p := &EnergyParams{}
+	for i := range p.stack {
+		for j := range p.stack[i] {
+			p.stack[i][j] = inf
+		}
+	}
+	for i := range p.hairpin {
+		p.hairpin[i], p.bulge[i], p.interior[i] = inf, inf, inf
+	}
+	var section string
+	var header []int
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.HasPrefix(fields[0], "[") {
+			section = strings.Trim(fields[0], "[]")
+			header = nil
+			continue
+		}
+		var err error
+		switch section {
+		case "stack":
+			header, err = p.parseStackLine(fields, header)
+		case "hairpin":
+			err = parseLoopLine(fields, p.hairpin[:])
+		case "bulge":
+			err = parseLoopLine(fields, p.bulge[:])
+		case "interior":
+			err = parseLoopLine(fields, p.interior[:])
+		case "misc":
+			err = p.parseMiscLine(fields)
+		default:
+			err = fmt.Errorf("unknown section %q", section)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rnafold: energy params line %d: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *EnergyParams) parseStackLine(fields []string, header []int) ([]int, error) {
+	if header == nil {
+		for _, f := range fields {
+			t, ok := pairIndex(f)
+			if !ok {
+				return nil, fmt.Errorf("unknown pair %q", f)
+			}
+			header = append(header, t)
+		}
+		return header, nil
+	}
+	outer, ok := pairIndex(fields[0])
+	if !ok {
+		return nil, fmt.Errorf("unknown pair %q", fields[0])
+	}
+	if len(fields)-1 != len(header) {
+		return nil, fmt.Errorf("expected %d values, found %d", len(header), len(fields)-1)
+	}
+	for k, f := range fields[1:] {
+		e, err := parseEnergy(f)
+		if err != nil {
+			return nil, err
+		}
+		p.stack[outer][header[k]] = e
+	}
+	return header, nil
+}
+
+func parseLoopLine(fields []string, table []int) error {
+	if len(fields) != 2 {
+		return fmt.Errorf("expected size and energy")
+	}
+	size, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return err
+	}
+	if size < 0 || size >= len(table) {
+		return fmt.Errorf("loop size %d out of range", size)
+	}
+	table[size], err = parseEnergy(fields[1])
+	return err
+}
+
+func (p *EnergyParams) parseMiscLine(fields []string) error {
+	if len(fields) != 2 {
+		return fmt.Errorf("expected name and value")
+	}
+	if fields[0] == "lxc" {
+		v, err := strconv.ParseFloat(fields[1], 64)
+		p.lxc = v * 100
+		return err
+	}
+	v, err := parseEnergy(fields[1])
+	if err != nil {
+		return err
+	}
+	switch fields[0] {
+	case "terminal_au":
+		p.terminalAU = v
+	case "ninio":
+		p.ninio = v
+	case "ninio_max":
+		p.ninioMax = v
+	case "ml_closing":
+		p.mlClosing = v
+	case "ml_unpaired":
+		p.mlUnpaired = v
+	case "ml_branch":
+		p.mlBranch = v
+	case "duplex_init":
+		p.duplexInit = v
+	default:
+		return fmt.Errorf("unknown parameter %q", fields[0])
+	}
+	return nil
+}
+
+// parseEnergy converts kcal/mol text to dcal/mol.
+func parseEnergy(s string) (int, error) {
+	if s == "inf" {
+		return inf, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Floor(v*100 + 0.5)), nil
+}
+
+func pairIndex(s string) (int, bool) {
+	for t, name := range pairNames {
+		if name == s {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// encode converts a sequence to nucleotide codes, normalizing it first.
+func encode(seq string) ([]int, error) {
+	seq, err := Normalize(seq)
+	if err != nil {
+		return nil, err
+	}
+	enc := make([]int, len(seq))
+	for i := 0; i < len(seq); i++ {
+		switch seq[i] {
+		case 'A':
+			enc[i] = nucA
+		case 'C':
+			enc[i] = nucC
+		case 'G':
+			enc[i] = nucG
+		case 'U':
+			enc[i] = nucU
+		default:
+			return nil, fmt.Errorf("rnafold: invalid nucleotide %q at position %d", seq[i], i)
+		}
+	}
+	return enc, nil
+}
+
+// energyModel evaluates loop energies for an encoded sequence.
+type energyModel struct {
+	seq     []int
+	p       *EnergyParams
+	opts    Options
+	minLoop int
+	cons    *constraint
+	shape   []int // SHAPE pseudo-energy of every base, nil without data
+	// linker bases [linkStart, linkEnd) join two strands when cofolding
+	linkStart, linkEnd int
+}
+
+func newEnergyModel(seq string, opts []Options) (*energyModel, error) {
+	enc, err := encode(seq)
+	if err != nil {
+		return nil, err
+	}
+	o, err := optionsOf(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newEncodedModel(enc, o)
+}
+
+func newEncodedModel(enc []int, o Options) (*energyModel, error) {
+	p := o.Params
+	if p == nil {
+		p = defaultParams
+	}
+	minLoop := o.MinLoop
+	if minLoop < minHairpin {
+		minLoop = minHairpin
+	}
+	cons, err := newConstraint(o.Constraints, len(enc))
+	if err != nil {
+		return nil, err
+	}
+	m := &energyModel{seq: enc, p: p, opts: o, minLoop: minLoop, cons: cons}
+	if o.Shape != nil {
+		if m.shape, err = o.Shape.pseudoEnergies(len(enc)); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// pairType returns the type of the pair (i, j), or -1 if they cannot pair.
+func (m *energyModel) pairType(i, j int) int {
+	a, b := m.seq[i], m.seq[j]
+	switch {
+	case a == nucA && b == nucU:
+		return pairAU
+	case a == nucC && b == nucG:
+		return pairCG
+	case a == nucG && b == nucC:
+		return pairGC
+	case a == nucU && b == nucA:
+		return pairUA
+	case m.opts.NoWobble:
+		return -1
+	case a == nucG && b == nucU:
+		return pairGU
+	case a == nucU && b == nucG:
+		return pairUG
+	}
+	return -1
+}
+
+func (m *energyModel) canPair(i, j int) bool {
+	return j-i > m.minLoop && m.pairType(i, j) >= 0 && m.cons.allowPair(i, j)
+}
+
+// terminal returns the AU/GU penalty of a helix ending in (i, j).
+func (m *energyModel) terminal(i, j int) int {
+	if t := m.pairType(i, j); t != pairCG && t != pairGC {
+		return m.p.terminalAU
+	}
+	return 0
+}
+
+func (m *energyModel) loopTable(table []int, size int) int {
+	if size <= maxLoop {
+		return table[size]
+	}
+	return table[maxLoop] + int(m.p.lxc*math.Log(float64(size)/maxLoop))
+}
+
+// hairpin returns the energy of the hairpin loop closed by (i, j).
+func (m *energyModel) hairpin(i, j int) int {
+	size := j - i - 1
+	if size < m.minLoop || !m.cons.canSkip(i+1, j-1) || m.holdsLinker(i+1, j-1) {
+		return inf
+	}
+	return m.loopTable(m.p.hairpin[:], size) + m.terminal(i, j)
+}
+
+// interior returns the energy of the stack, bulge or interior loop
+// closed by the outer pair (i, j) and the inner pair (k, l).
+func (m *energyModel) interior(i, j, k, l int) int {
+	u1, u2 := k-i-1, j-l-1
+	if !m.cons.canSkip(i+1, k-1) || !m.cons.canSkip(l+1, j-1) ||
+		m.holdsLinker(i+1, k-1) || m.holdsLinker(l+1, j-1) {
+		return inf
+	}
+	outer, inner := m.pairType(i, j), m.pairType(k, l)
+	switch {
+	case u1 == 0 && u2 == 0:
+		e := m.p.stack[outer][inner]
+		if m.shape != nil {
+			e += m.shape[i] + m.shape[j] + m.shape[k] + m.shape[l]
+		}
+		return e
+	case u1 == 0 || u2 == 0:
+		size := u1 + u2
+		e := m.loopTable(m.p.bulge[:], size)
+		if size == 1 {
+			return e + m.p.stack[outer][inner]
+		}
+		return e + m.terminal(i, j) + m.terminal(k, l)
+	}
+	asym := u1 - u2
+	if asym < 0 {
+		asym = -asym
+	}
+	ninio := asym * m.p.ninio
+	if ninio > m.p.ninioMax {
+		ninio = m.p.ninioMax
+	}
+	return m.loopTable(m.p.interior[:], u1+u2) + ninio +
+		m.terminal(i, j) + m.terminal(k, l)
+}
+
+// mlGap returns the energy of the k unpaired multiloop bases starting at a.
+func (m *energyModel) mlGap(a, k int) int {
+	if !m.cons.canSkip(a, a+k-1) || m.holdsLinker(a, a+k-1) {
+		return inf
+	}
+	return k * m.p.mlUnpaired
+}
+
+// holdsLinker reports whether the unpaired bases [a, b] include part of the
+// linker. Loops holding the linker are exterior loops, so it may not sit in a
+// hairpin, interior loop or multiloop.
+func (m *energyModel) holdsLinker(a, b int) bool {
+	return m.linkEnd > 0 && a <= b && a < m.linkEnd && b >= m.linkStart
+}
+
+// EvalEnergy returns the free energy in kcal/mol of a secondary structure
+// of seq under the nearest-neighbor model (Options.Params or the default),
+// including SHAPE pseudo-energies if Options.Shape is set.
+// Constraints are ignored. The structure must be nested, without
+// pseudoknots, and its hairpins must hold at least Options.MinLoop
+// unpaired bases (3 at least); EvalEnergy returns an error otherwise.
+func EvalEnergy(seq string, s *Structure, opts ...Options) (float64, error) {
+	m, err := newEnergyModel(seq, opts)
+	if err != nil {
+		return 0, err
+	}
+	m.cons = nil
+	if s.Length != len(seq) {
+		return 0, fmt.Errorf("rnafold: structure length %d differs from sequence length %d",
+			s.Length, len(seq))
+	}
+	pt, err := pairTable(s)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range s.Pairs {
+		if m.pairType(p.I, p.J) < 0 {
+			return 0, fmt.Errorf("rnafold: (%d, %d) is not a valid pair", p.I, p.J)
+		}
+		if p.J-p.I-1 < m.minLoop {
+			return 0, fmt.Errorf("rnafold: (%d, %d) encloses a hairpin of fewer than %d bases",
+				p.I, p.J, m.minLoop)
+		}
+	}
+	e := 0
+	for i := 0; i < len(pt); i++ {
+		if j := pt[i]; j > i {
+			e += m.terminal(i, j) + m.evalPair(i, j, pt)
+			i = j
+		}
+	}
+	return float64(e) / 100, nil
+}
+
+// evalPair returns the energy of the loop closed by (i, j) and everything inside it.
+func (m *energyModel) evalPair(i, j int, pt []int) int {
+	var branches []Pair
+	unpaired := 0
+	for k := i + 1; k < j; k++ {
+		if l := pt[k]; l > k {
+			branches = append(branches, Pair{I: k, J: l})
+			k = l
+		} else {
+			unpaired++
+		}
+	}
+	switch len(branches) {
+	case 0:
+		return m.hairpin(i, j)
+	case 1:
+		b := branches[0]
+		return m.interior(i, j, b.I, b.J) + m.evalPair(b.I, b.J, pt)
+	}
+	e := m.p.mlClosing + m.p.mlBranch + m.terminal(i, j) + unpaired*m.p.mlUnpaired
+	for _, b := range branches {
+		e += m.p.mlBranch + m.terminal(b.I, b.J) + m.evalPair(b.I, b.J, pt)
+	}
+	return e
+}
+
+// turner2004 is a simplified Turner 2004 parameter set: terminal mismatches,
+// dangles, special hairpins and the small interior loop tables are replaced
+// by the terminal AU/GU penalty and generic loop initiation terms.
+const turner2004 = `
+# nearest-neighbor parameters, kcal/mol at 37C
+
+[stack]
+# rows: outer pair i-j, columns: inner pair (i+1)-(j-1)
+      GC     CG     UG     GU     UA     AU
+CG  -2.40  -3.30  -2.10  -1.40  -2.10  -2.10
+GC  -3.30  -3.40  -2.50  -1.50  -2.20  -2.40
+GU  -2.10  -2.50   1.30  -0.50  -1.40  -1.30
+UG  -1.40  -1.50  -0.50   0.30  -0.60  -1.00
+AU  -2.10  -2.20  -1.40  -0.60  -1.10  -0.90
+UA  -2.10  -2.40  -1.30  -1.00  -0.90  -1.30
+
+[hairpin]
+3 5.40
+4 5.60
+5 5.70
+6 5.40
+7 6.00
+8 5.50
+9 6.40
+10 6.50
+11 6.60
+12 6.70
+13 6.78
+14 6.86
+15 6.94
+16 7.01
+17 7.07
+18 7.13
+19 7.19
+20 7.25
+21 7.30
+22 7.35
+23 7.40
+24 7.44
+25 7.49
+26 7.53
+27 7.57
+28 7.61
+29 7.65
+30 7.69
+
+[bulge]
+1 3.80
+2 2.80
+3 3.20
+4 3.60
+5 4.00
+6 4.40
+7 4.59
+8 4.70
+9 4.80
+10 4.90
+11 5.00
+12 5.10
+13 5.20
+14 5.30
+15 5.40
+16 5.50
+17 5.60
+18 5.70
+19 5.80
+20 5.90
+21 6.00
+22 6.10
+23 6.20
+24 6.30
+25 6.40
+26 6.50
+27 6.60
+28 6.70
+29 6.80
+30 6.90
+
+[interior]
+2 0.50
+3 1.60
+4 1.10
+5 2.00
+6 2.00
+7 2.10
+8 2.30
+9 2.40
+10 2.50
+11 2.60
+12 2.70
+13 2.80
+14 2.90
+15 2.90
+16 3.00
+17 3.10
+18 3.10
+19 3.20
+20 3.30
+21 3.30
+22 3.40
+23 3.40
+24 3.50
+25 3.50
+26 3.50
+27 3.60
+28 3.60
+29 3.70
+30 3.70
+
+[misc]
+terminal_au 0.50
+ninio 0.60
+ninio_max 3.00
+ml_closing 3.40
+ml_unpaired 0.00
+ml_branch 0.40
+lxc 1.07856
+duplex_init 4.10
+`
@@ -0,0 +1,126 @@
+package rnafold
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMFEHairpin(t *testing.T) {
+	e, s, err := MFE("GGGGAAACCCC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(e, s.DotBracket())
+	if s.DotBracket() != "((((...))))" || math.Abs(e-(-4.5)) > 1e-9 {
+		t.Fatalf("got %v %v, want -4.5 ((((...))))", e, s.DotBracket())
+	}
+}
+
+func TestMFEFixtures(t *testing.T) {
+	for _, seq := range []string{mIR1976, rNA5SP136, bCYRN1} {
+		e, s, err := MFE(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Log(e, s.DotBracket())
+		eval, err := EvalEnergy(seq, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(e-eval) > 1e-9 {
+			t.Fatalf("MFE %v differs from evaluated structure energy %v", e, eval)
+		}
+	}
+}
+
+func TestEvalEnergyInvalid(t *testing.T) {
+	seq := "GGGGAAACCCC"
+	for _, pairs := range [][]Pair{
+		{{0, 11}},          // out of range
+		{{10, 0}},          // reversed
+		{{0, 10}, {0, 10}}, // duplicate
+		{{0, 10}, {1, 10}}, // shared base
+		{{0, 8}, {2, 10}},  // crossing
+	} {
+		if _, err := EvalEnergy(seq, &Structure{Length: len(seq), Pairs: pairs}); err == nil {
+			t.Errorf("%v: expected an error", pairs)
+		}
+	}
+	// hairpins too short for the model
+	for _, pairs := range [][]Pair{{{0, 3}}, {{0, 1}}, {{0, 7}, {2, 3}}} {
+		if _, err := EvalEnergy("GCGCAAAC", &Structure{Length: 8, Pairs: pairs}); err == nil {
+			t.Errorf("%v: expected an error", pairs)
+		}
+	}
+	// and one of 4 bases under MinLoop 5
+	s := &Structure{Length: len(seq), Pairs: []Pair{{2, 7}}}
+	if _, err := EvalEnergy(seq, s, Options{MinLoop: 5}); err == nil {
+		t.Error("MinLoop 5: expected an error")
+	}
+}
+
+// TestMFEExhaustive compares MFE against every structure of short sequences.
+func TestMFEExhaustive(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for trial := 0; trial < 30; trial++ {
+		seq := randomRNA(r, 8+r.Intn(8))
+		e, _, err := MFE(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		best := 0.0
+		for _, s := range allStructures(seq, minHairpin) {
+			eval, err := EvalEnergy(seq, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			best = math.Min(best, eval)
+		}
+		if math.Abs(e-best) > 1e-9 {
+			t.Fatalf("%v: MFE %v, exhaustive minimum %v", seq, e, best)
+		}
+	}
+}
+
+func TestLoadEnergyParams(t *testing.T) {
+	if _, err := LoadEnergyParams(strings.NewReader(turner2004)); err != nil {
+		t.Fatal(err)
+	}
+	bad := "[stack]\n  GC  CG\nCG -2.4\n"
+	if _, err := LoadEnergyParams(strings.NewReader(bad)); err == nil {
+		t.Fatal("expected an error for a short stack row")
+	}
+	if e, _, err := MFE("ggggaaacccc"); err != nil || math.Abs(e-(-4.5)) > 1e-9 {
+		t.Fatalf("lower case MFE %v %v, want -4.5", e, err)
+	}
+	if _, _, err := MFE("GGGAAACCX"); err == nil {
+		t.Fatal("expected an error for an invalid nucleotide")
+	}
+}
+
+// allStructures enumerates every nested secondary structure of seq.
+func allStructures(seq string, minLoop int) (structs []*Structure) {
+	var rec func(i int, pairs []Pair, open []int)
+	m := &energyModel{opts: Options{}}
+	m.seq, _ = encode(seq)
+	rec = func(i int, pairs []Pair, open []int) {
+		if i == len(seq) {
+			if len(open) == 0 {
+				structs = append(structs, NewStructure(len(seq), append([]Pair(nil), pairs...)))
+			}
+			return
+		}
+		rec(i+1, pairs, open)
+		rec(i+1, pairs, append(open[:len(open):len(open)], i))
+		if len(open) > 0 {
+			j := open[len(open)-1]
+			if i-j > minLoop && m.pairType(j, i) >= 0 {
+				rec(i+1, append(pairs[:len(pairs):len(pairs)], Pair{I: j, J: i}), open[:len(open)-1])
+			}
+		}
+	}
+	rec(0, nil, nil)
+	return
+}
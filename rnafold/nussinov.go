@@ -1,25 +1,106 @@
 package rnafold
 
 import (
+	"sync"
+
 	bio "github.com/bsjcho/bioinf"
 )
 
 // RNA secondary structure using the Nussinov algorithm.
 // Input:
-// - A sequence composed of nucleotide bases (A,C,G,T)
+// - A sequence composed of nucleotide bases (A,C,G,U, or DNA with T read as U)
 // Output:
 // - The number of base pair matchings
+// - The secondary structure realizing them (Fold)
 
 type nussinov struct {
 	sequence string
 	matrix   [][]int
+	opts     Options
+	cons     *constraint
+}
+
+func newNussinov(seq string, opts []Options) (*nussinov, error) {
+	rna, err := Normalize(seq)
+	if err != nil {
+		return nil, err
+	}
+	o, err := optionsOf(opts)
+	if err != nil {
+		return nil, err
+	}
+	cons, err := newConstraint(o.Constraints, len(rna))
+	if err != nil {
+		return nil, err
+	}
+	return &nussinov{sequence: rna, opts: o, cons: cons}, nil
+}
+
+// result returns the score of the whole sequence, or errInfeasible if no
+// structure satisfies the constraints.
+func (n *nussinov) result() (int, error) {
+	score := foldScore(0, len(n.sequence)-1, n)
+	if score < 0 {
+		return 0, errInfeasible
+	}
+	return score, nil
 }
 
 // FoldScore performs the nussinov algorithm on a sequence, returning the max pairs.
-func FoldScore(seq string) (pairCount int) {
-	n := &nussinov{sequence: seq}
+// A single Options value may be given to change the pairing rules, in which
+// case the result is the max total pair weight; more than one is an error.
+func FoldScore(seq string, opts ...Options) (score int, err error) {
+	n, err := newNussinov(seq, opts)
+	if err != nil {
+		return 0, err
+	}
 	initialize(n)
-	return foldScore(0, len(n.sequence)-1, n)
+	return n.result()
+}
+
+// FoldScoreSequence is FoldScore for a bio.Sequence.
+func FoldScoreSequence(seq *bio.Sequence, opts ...Options) (score int, err error) {
+	rna, err := SequenceToRNA(seq)
+	if err != nil {
+		return 0, err
+	}
+	return FoldScore(rna, opts...)
+}
+
+// Fold performs the nussinov algorithm on a sequence, returning the max pairs
+// and a secondary structure with that many pairs.
+// A single Options value may be given to change the pairing rules; more
+// than one is an error.
+func Fold(seq string, opts ...Options) (score int, s *Structure, err error) {
+	n, err := newNussinov(seq, opts)
+	if err != nil {
+		return 0, nil, err
+	}
+	n.matrix = bio.Slice2D(len(seq), len(seq), 0)
+	fill(n, 1)
+	if score, err = n.result(); err != nil {
+		return 0, nil, err
+	}
+	pairs := traceback(0, len(n.sequence)-1, n, nil)
+	return score, NewStructure(len(seq), pairs), nil
+}
+
+// IterativeFoldScore returns the same score as FoldScore, filling the matrix
+// bottom-up one diagonal at a time instead of recursing.
+func IterativeFoldScore(seq string, opts ...Options) (int, error) {
+	return ParallelFoldScore(seq, 1, opts...)
+}
+
+// ParallelFoldScore is IterativeFoldScore with the cells of each diagonal
+// split across workers goroutines.
+func ParallelFoldScore(seq string, workers int, opts ...Options) (int, error) {
+	n, err := newNussinov(seq, opts)
+	if err != nil {
+		return 0, err
+	}
+	n.matrix = bio.Slice2D(len(seq), len(seq), 0)
+	fill(n, workers)
+	return n.result()
 }
 
 func initialize(n *nussinov) {
@@ -28,10 +109,14 @@ func initialize(n *nussinov) {
 }
 
 func foldScore(i, j int, n *nussinov) int {
-	if j-i < 2 {
-		return 0
+	if j-i <= n.opts.MinLoop {
+		return n.short(i, j)
 	}
 	if n.matrix[i][j] == -1 {
+		if n.cons != nil {
+			n.matrix[i][j] = n.constrainedCell(i, j, foldScore)
+			return n.matrix[i][j]
+		}
 		n.matrix[i][j] = bio.Max(
 			foldScore(i+1, j, n),
 			foldScore(i, j-1, n),
@@ -46,10 +131,124 @@ func foldScore(i, j int, n *nussinov) int {
 	return n.matrix[i][j]
 }
 
+// traceback walks the filled matrix from (i, j), appending the pairs of an
+// optimal structure to pairs.
+func traceback(i, j int, n *nussinov, pairs []Pair) []Pair {
+	if j-i <= n.opts.MinLoop {
+		return pairs
+	}
+	score := foldScore(i, j, n)
+	switch {
+	case n.cons.canSkip(i, i) && score == foldScore(i+1, j, n):
+		return traceback(i+1, j, n, pairs)
+	case n.cons.canSkip(j, j) && score == foldScore(i, j-1, n):
+		return traceback(i, j-1, n, pairs)
+	case matchScore(i, j, n) > 0 &&
+		score == foldScore(i+1, j-1, n)+matchScore(i, j, n):
+		pairs = append(pairs, Pair{I: i, J: j})
+		return traceback(i+1, j-1, n, pairs)
+	}
+	for k := i + 1; k < j; k++ {
+		if score == foldScore(i, k, n)+foldScore(k+1, j, n) {
+			pairs = traceback(i, k, n, pairs)
+			return traceback(k+1, j, n, pairs)
+		}
+	}
+	return pairs
+}
+
+// diagonals shorter than this are not worth splitting across goroutines
+const minParallelCells = 256
+
+// fill computes the matrix bottom-up, one diagonal at a time. The cells of a
+// diagonal only depend on shorter diagonals, so they can be computed
+// concurrently. Each score is mirrored into the lower triangle (matrix[j][i])
+// so that both operands of the split loop are read along rows.
+func fill(n *nussinov, workers int) {
+	size := len(n.sequence)
+	for d := n.opts.MinLoop + 1; d < size; d++ {
+		cells := size - d
+		if workers <= 1 || cells < minParallelCells {
+			fillCells(n, d, 0, cells)
+			continue
+		}
+		var wg sync.WaitGroup
+		chunk := (cells + workers - 1) / workers
+		for lo := 0; lo < cells; lo += chunk {
+			wg.Add(1)
+			go func(lo, hi int) {
+				defer wg.Done()
+				fillCells(n, d, lo, hi)
+			}(lo, bio.Min(lo+chunk, cells))
+		}
+		wg.Wait()
+	}
+}
+
+// fillCells computes the cells (i, i+d) of diagonal d for lo <= i < hi.
+func fillCells(n *nussinov, d, lo, hi int) {
+	m := n.matrix
+	for i := lo; i < hi; i++ {
+		j := i + d
+		if n.cons != nil {
+			best := n.constrainedCell(i, j, func(i, j int, n *nussinov) int {
+				if j-i <= n.opts.MinLoop {
+					return n.short(i, j)
+				}
+				return m[i][j]
+			})
+			m[i][j], m[j][i] = best, best
+			continue
+		}
+		best := bio.Max(m[i+1][j], m[i][j-1], m[i+1][j-1]+matchScore(i, j, n))
+		row, col := m[i], m[j]
+		for k := i + 1; k < j; k++ {
+			if s := row[k] + col[k+1]; s > best {
+				best = s
+			}
+		}
+		m[i][j], m[j][i] = best, best
+	}
+}
+
+// constrainedCell computes S(i,j) under constraints, reading smaller
+// intervals through score. Bases with a forced partner may not be left
+// unpaired, so intervals cutting a forced pair come out negative.
+func (n *nussinov) constrainedCell(i, j int, score func(i, j int, n *nussinov) int) int {
+	best := impossible
+	if n.cons.canSkip(i, i) {
+		best = bio.Max(best, score(i+1, j, n))
+	}
+	if n.cons.canSkip(j, j) {
+		best = bio.Max(best, score(i, j-1, n))
+	}
+	if w := matchScore(i, j, n); w > 0 {
+		best = bio.Max(best, score(i+1, j-1, n)+w)
+	}
+	for k := i + 1; k < j; k++ {
+		best = bio.Max(best, score(i, k, n)+score(k+1, j, n))
+	}
+	return bio.Max(best, impossible)
+}
+
+// short returns the score of an interval too short to hold a pair.
+func (n *nussinov) short(i, j int) int {
+	if n.cons.canSkip(i, j) {
+		return 0
+	}
+	return impossible
+}
+
 func matchScore(i, j int, n *nussinov) int {
-	ib := string(n.sequence[i])
-	jb := string(n.sequence[j])
-	t := ib + jb
+	if !n.cons.allowPair(i, j) {
+		return 0
+	}
+	return n.opts.pairWeight(n.sequence[i], n.sequence[j])
+}
+
+// pairScore returns 1 if the two bases form a Watson-Crick or GU wobble pair.
+func pairScore(ib, jb byte) int {
+	t := string(ib) + string(jb)
 	res := t == "AU" || t == "CG" || t == "GU" ||
 		t == "UA" || t == "GC" || t == "UG"
 	if res {
@@ -1,6 +1,13 @@
 package rnafold
 
-import "testing"
+import (
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+
+	bio "github.com/bsjcho/bioinf"
+)
 
 const (
 	mIR1976   = "GCAGCAAGGAAGGCAGGGGUCCUAAGGUGUGUCCUCCUGCCCUCCUUGCUGU"
@@ -9,23 +16,195 @@ const (
 )
 
 func TestSolve(t *testing.T) {
-	t1 := FoldScore(mIR1976)
+	t1 := mustScore(t, FoldScore, mIR1976)
 	t.Log(t1)
 	if t1 != 23 {
 		t.Fatal("Solving mIR1976 failed.")
 	}
-	t2 := FoldScore(rNA5SP136)
+	t2 := mustScore(t, FoldScore, rNA5SP136)
 	t.Log(t2)
 	if t2 != 42 {
 		t.Fatal("Solving rNA5SP136 failed.")
 	}
-	t3 := FoldScore(bCYRN1)
+	t3 := mustScore(t, FoldScore, bCYRN1)
 	t.Log(t3)
 	if t3 != 69 {
 		t.Fatal("Solving bCYRN1 failed.")
 	}
 }
 
+func TestFold(t *testing.T) {
+	for _, seq := range []string{mIR1976, rNA5SP136, bCYRN1} {
+		score, s, err := Fold(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score != mustScore(t, FoldScore, seq) || len(s.Pairs) != score {
+			t.Fatalf("Fold score %v with %v pairs, want %v", score, len(s.Pairs), mustScore(t, FoldScore, seq))
+		}
+		db := s.DotBracket()
+		t.Log(db)
+		if len(db) != len(seq) {
+			t.Fatal("dot-bracket length differs from sequence length")
+		}
+		var stack []int
+		for i, c := range db {
+			switch c {
+			case '(':
+				stack = append(stack, i)
+			case ')':
+				if len(stack) == 0 {
+					t.Fatal("unbalanced dot-bracket", db)
+				}
+				j := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if pairScore(seq[j], seq[i]) != 1 || i-j < 2 {
+					t.Fatalf("invalid pair (%v, %v)", j, i)
+				}
+			}
+		}
+		if len(stack) != 0 {
+			t.Fatal("unbalanced dot-bracket", db)
+		}
+	}
+	if _, s, _ := Fold("GGGAAACCC"); s.DotBracket() != "(((...)))" {
+		t.Fatal("unexpected structure", s.DotBracket())
+	}
+}
+
+func TestOptions(t *testing.T) {
+	if mustScore(t, FoldScore, mIR1976, Options{}) != mustScore(t, FoldScore, mIR1976) {
+		t.Fatal("zero Options changed the default score")
+	}
+	// a minimum loop of three leaves no room for a pair in four bases
+	if score := mustScore(t, FoldScore, "GAAC", Options{MinLoop: 3}); score != 0 {
+		t.Fatalf("MinLoop 3 scored %v, want 0", score)
+	}
+	if score := mustScore(t, FoldScore, "GAAAC", Options{MinLoop: 3}); score != 1 {
+		t.Fatalf("MinLoop 3 scored %v, want 1", score)
+	}
+	if score := mustScore(t, FoldScore, "GGGGUUUU", Options{NoWobble: true}); score != 0 {
+		t.Fatalf("NoWobble scored %v, want 0", score)
+	}
+	weights := map[string]int{"GC": 3, "AU": 2, "GU": 1}
+	if score := mustScore(t, FoldScore, "GAAAUCAAAC", Options{Weights: weights}); score != 5 {
+		t.Fatalf("weighted fold scored %v, want 5", score)
+	}
+	score, s, _ := Fold("AAAAUCCCCC", Options{Weights: weights, MinLoop: 3})
+	if score != 2 || s.DotBracket() != "(...)....." {
+		t.Fatalf("weighted fold %v %v, want 2 (...).....", score, s.DotBracket())
+	}
+	// later values are rejected rather than ignored
+	if _, err := FoldScore("GAAAC", Options{}, Options{MinLoop: 3}); err == nil {
+		t.Fatal("FoldScore: expected an error for two Options")
+	}
+	if _, _, err := Fold("GAAAC", Options{}, Options{MinLoop: 3}); err == nil {
+		t.Fatal("Fold: expected an error for two Options")
+	}
+	if _, _, err := MFE("GAAAC", Options{}, Options{}); err == nil {
+		t.Fatal("MFE: expected an error for two Options")
+	}
+}
+
+func TestTVFRSolve(t *testing.T) {
+	t1 := mustScore(t, tvfrFoldScore, mIR1976)
+	t.Log(t1)
+	if t1 != 23 {
+		t.Fatal("TVFR solving mIR1976 failed.")
+	}
+	t2 := mustScore(t, tvfrFoldScore, rNA5SP136)
+	t.Log(t2)
+	if t2 != 42 {
+		t.Fatal("TVFR solving rNA5SP136 failed.")
+	}
+	t3 := mustScore(t, tvfrFoldScore, bCYRN1)
+	t.Log(t3)
+	if t3 != 69 {
+		t.Fatal("TVFR solving bCYRN1 failed.")
+	}
+}
+
+func TestTVFRMatchesFoldScore(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 120; n++ {
+		seq := randomRNA(r, n)
+		if a, b := mustScore(t, FoldScore, seq), mustScore(t, tvfrFoldScore, seq); a != b {
+			t.Fatalf("TVFR mismatch on %v: FoldScore=%v TVFR=%v", seq, a, b)
+		}
+	}
+}
+
+func TestIterativeMatchesFoldScore(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	seqs := []string{mIR1976, rNA5SP136, bCYRN1, randomRNA(r, 700)}
+	for n := 0; n < 40; n++ {
+		seqs = append(seqs, randomRNA(r, n))
+	}
+	weights := Options{Weights: map[string]int{"GC": 3, "AU": 2}, MinLoop: 3}
+	for _, seq := range seqs {
+		for _, o := range []Options{{}, weights} {
+			want := mustScore(t, FoldScore, seq, o)
+			if got := mustScore(t, IterativeFoldScore, seq, o); got != want {
+				t.Fatalf("IterativeFoldScore %v, FoldScore %v on %v", got, want, seq)
+			}
+			got, err := ParallelFoldScore(seq, 4, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Fatalf("ParallelFoldScore %v, FoldScore %v on %v", got, want, seq)
+			}
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	dna := "ggcagcaaggaaggcaggggtcctaaggtgtgtcctcctgccctccttgctgt"
+	if got := mustScore(t, FoldScore, dna); got != mustScore(t, FoldScore, strings.ToUpper(strings.Replace(dna, "t", "u", -1))) {
+		t.Fatalf("DNA input scored %v", got)
+	}
+	seq, err := bio.ParseSequence("GGGGAAAACCCC", bio.DNA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := FoldScoreSequence(seq); err != nil || got != 4 {
+		t.Fatalf("bio.Sequence input scored %v (%v), want 4", got, err)
+	}
+	if _, err := FoldScore("GGGNAAACCC"); err == nil {
+		t.Fatal("expected an error for N")
+	}
+	gapped, _ := bio.ParseSequence("GG-AAACC", bio.DNA)
+	if _, err := FoldScoreSequence(gapped); err == nil {
+		t.Fatal("expected an error for a gap")
+	}
+	if _, err := TVFRFoldScore("GGAXCC"); err == nil {
+		t.Fatal("expected an error for X")
+	}
+}
+
+// mustScore returns the score of fold on seq, failing the test on an error.
+func mustScore(t *testing.T, fold func(string, ...Options) (int, error), seq string, opts ...Options) int {
+	t.Helper()
+	score, err := fold(seq, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return score
+}
+
+// tvfrFoldScore is TVFRFoldScore with the signature of FoldScore.
+func tvfrFoldScore(seq string, _ ...Options) (int, error) {
+	return TVFRFoldScore(seq)
+}
+
+func randomRNA(r *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = "ACGU"[r.Intn(4)]
+	}
+	return string(b)
+}
+
 func BenchmarkMIR1976(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		FoldScore(mIR1976)
@@ -43,3 +222,79 @@ func BenchmarkBCYRN1(b *testing.B) {
 		FoldScore(bCYRN1)
 	}
 }
+
+func BenchmarkTVFRMIR1976(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		TVFRFoldScore(mIR1976)
+	}
+}
+
+func BenchmarkTVFRRNA5SP136(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		TVFRFoldScore(rNA5SP136)
+	}
+}
+
+func BenchmarkTVFRBCYRN1(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		TVFRFoldScore(bCYRN1)
+	}
+}
+
+var (
+	rna2k  = randomRNA(rand.New(rand.NewSource(2000)), 2000)
+	rna5k  = randomRNA(rand.New(rand.NewSource(5000)), 5000)
+	rna10k = randomRNA(rand.New(rand.NewSource(10000)), 10000)
+)
+
+// skipLarge skips benchmarks folding rna10k whole, whose table takes
+// about 800 MB, under -short.
+func skipLarge(b *testing.B) {
+	if testing.Short() {
+		b.Skip("10k nt table takes about 800 MB")
+	}
+}
+
+func BenchmarkFoldScore2k(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		FoldScore(rna2k)
+	}
+}
+
+func BenchmarkIterative2k(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		IterativeFoldScore(rna2k)
+	}
+}
+
+func BenchmarkIterative5k(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		IterativeFoldScore(rna5k)
+	}
+}
+
+func BenchmarkIterative10k(b *testing.B) {
+	skipLarge(b)
+	for i := 0; i < b.N; i++ {
+		IterativeFoldScore(rna10k)
+	}
+}
+
+func BenchmarkParallel2k(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParallelFoldScore(rna2k, runtime.NumCPU())
+	}
+}
+
+func BenchmarkParallel5k(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParallelFoldScore(rna5k, runtime.NumCPU())
+	}
+}
+
+func BenchmarkParallel10k(b *testing.B) {
+	skipLarge(b)
+	for i := 0; i < b.N; i++ {
+		ParallelFoldScore(rna10k, runtime.NumCPU())
+	}
+}
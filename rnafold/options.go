@@ -0,0 +1,71 @@
+package rnafold
+
+import "fmt"
+
+// Options configures folding. Fields that do not apply to an algorithm are
+// ignored by it. The zero value reproduces the defaults:
+// pairs enclose at least one unpaired base, GU wobble pairs are allowed
+// and every valid pair scores one.
+//
+// Functions taking opts ...Options accept at most one value, so that
+// Options may be omitted; passing more than one is an error.
+type Options struct {
+	// MinLoop is the minimum number of unpaired bases enclosed by a pair.
+	// Zero means the default of one.
+	MinLoop int
+	// NoWobble disallows GU and UG pairs.
+	NoWobble bool
+	// Weights overrides the score of a pair, ie. {"GC": 3, "AU": 2, "GU": 1}.
+	// Keys are symmetric ("GC" also scores CG). Valid pairs missing from
+	// the map score one, a weight of zero forbids the pair.
+	Weights map[string]int
+	// Params are the nearest-neighbor parameters used by the energy folders.
+	// Nil means DefaultEnergyParams. Energy folders never allow hairpin
+	// loops shorter than three bases whatever MinLoop is.
+	Params *EnergyParams
+	// Constraints force, forbid or prevent pairs. They are honored by the
+	// Nussinov and energy folders; FoldPseudoknot only uses them to rule
+	// out pairs and scans do not support them.
+	Constraints *Constraints
+	// Shape adds SHAPE reactivity pseudo-energies to the stacked pairs of
+	// the energy folders. It must cover every base of the sequence.
+	Shape *Shape
+}
+
+// optionsOf returns the single value of opts, or the defaults if there
+// are none. More than one value is an error rather than being merged.
+func optionsOf(opts []Options) (Options, error) {
+	var o Options
+	switch len(opts) {
+	case 0:
+	case 1:
+		o = opts[0]
+	default:
+		return o, fmt.Errorf("rnafold: %d Options values given, expected at most one", len(opts))
+	}
+	if o.MinLoop <= 0 {
+		o.MinLoop = 1
+	}
+	return o, nil
+}
+
+// pairWeight returns the score of pairing ib with jb under these options,
+// zero if they cannot pair.
+func (o *Options) pairWeight(ib, jb byte) int {
+	if pairScore(ib, jb) == 0 {
+		return 0
+	}
+	if o.NoWobble && (ib == 'G' && jb == 'U' || ib == 'U' && jb == 'G') {
+		return 0
+	}
+	if o.Weights == nil {
+		return 1
+	}
+	if w, ok := o.Weights[string(ib)+string(jb)]; ok {
+		return w
+	}
+	if w, ok := o.Weights[string(jb)+string(ib)]; ok {
+		return w
+	}
+	return 1
+}
@@ -0,0 +1,267 @@
+package rnafold
+
+import "math"
+
+// RNA secondary structure ensemble using the McCaskill algorithm.
+// Input:
+// - A sequence composed of nucleotide bases (A,C,G,U)
+// Output:
+// - The ensemble free energy in kcal/mol
+// - The probability of every base pair in the Boltzmann ensemble
+//
+// The inside tables QB, QM, QM1, Q5 mirror the V, WM, WM1, W5 tables of the
+// Zuker algorithm with min/+ replaced by +/* over Boltzmann weights. The
+// outside tables OB, OM, OM1 are filled from the longest span down by
+// pushing each entry back through the inside recursion.
+// All weights of a span of k bases are scaled by scale[k] to keep long
+// sequences within floating point range.
+
+// kT at 37°C in dcal/mol
+const kT = 61.632077549999997
+
+type mccaskill struct {
+	*energyModel
+	n             int
+	qb, qm, qm1   [][]float64
+	q5, q3        []float64 // q5[j] covers [0,j), q3[i] covers [i,n)
+	ob, om, om1   [][]float64
+	scale         []float64 // scale[k] is pfScale^-k
+	pfScale       float64
+	expMLUnpaired []float64 // expMLUnpaired[k] weighs k unpaired multiloop bases
+}
+
+// Ensemble is the Boltzmann ensemble of secondary structures of a sequence.
+type Ensemble struct {
+	// FreeEnergy is the ensemble free energy -kT ln Z in kcal/mol.
+	FreeEnergy float64
+	// Probs[i][j] is the probability that bases i and j are paired.
+	// The matrix is symmetric.
+	Probs [][]float64
+
+	pf *mccaskill // tables kept for sampling
+}
+
+// PartitionFunction computes the McCaskill partition function of seq,
+// returning the ensemble free energy and the base pair probability matrix.
+func PartitionFunction(seq string, opts ...Options) (*Ensemble, error) {
+	m, err := newEnergyModel(seq, opts)
+	if err != nil {
+		return nil, err
+	}
+	pf := newMcCaskill(m)
+	if pf.pfScale == 0 {
+		return nil, errInfeasible
+	}
+	pf.inside()
+	pf.outside()
+	return pf.ensemble(), nil
+}
+
+func newMcCaskill(m *energyModel) *mccaskill {
+	n := len(m.seq)
+	pf := &mccaskill{
+		energyModel: m,
+		n:           n,
+		qb:          slice2DFloat(n, n),
+		qm:          slice2DFloat(n, n),
+		qm1:         slice2DFloat(n, n),
+		q5:          make([]float64, n+1),
+		q3:          make([]float64, n+1),
+		ob:          slice2DFloat(n, n),
+		om:          slice2DFloat(n, n),
+		om1:         slice2DFloat(n, n),
+		pfScale:     1,
+	}
+	// estimate the per base scale from the minimum free energy
+	if n > 0 {
+		z := newZuker(m)
+		z.fill()
+		if z.w5[n] >= inf {
+			pf.pfScale = 0
+			return pf
+		}
+		pf.pfScale = math.Exp(-1.07 * float64(z.w5[n]) / kT / float64(n))
+	}
+	pf.scale = make([]float64, n+1)
+	pf.expMLUnpaired = make([]float64, n+1)
+	for k := range pf.scale {
+		pf.scale[k] = math.Pow(pf.pfScale, -float64(k))
+		pf.expMLUnpaired[k] = boltzmann(k*m.p.mlUnpaired) * pf.scale[k]
+	}
+	return pf
+}
+
+// expMLGap weighs the k unpaired multiloop bases starting at a.
+func (pf *mccaskill) expMLGap(a, k int) float64 {
+	if !pf.cons.canSkip(a, a+k-1) {
+		return 0
+	}
+	return pf.expMLUnpaired[k]
+}
+
+// expExtGap weighs base i left unpaired in the exterior loop.
+func (pf *mccaskill) expExtGap(i int) float64 {
+	if !pf.cons.canSkip(i, i) {
+		return 0
+	}
+	return pf.scale[1]
+}
+
+func boltzmann(e int) float64 {
+	if e >= inf {
+		return 0
+	}
+	return math.Exp(-float64(e) / kT)
+}
+
+func slice2DFloat(rows, cols int) [][]float64 {
+	m := make([][]float64, rows)
+	x := make([]float64, rows*cols)
+	for i := range m {
+		m[i], x = x[:cols], x[cols:]
+	}
+	return m
+}
+
+func (pf *mccaskill) inside() {
+	for d := pf.minLoop + 1; d < pf.n; d++ {
+		for i := 0; i+d < pf.n; i++ {
+			j := i + d
+			pf.qb[i][j] = pf.insideB(i, j)
+			pf.qm1[i][j] = pf.insideM1(i, j)
+			pf.qm[i][j] = pf.insideM(i, j)
+		}
+	}
+	pf.q5[0] = 1
+	for j := 0; j < pf.n; j++ {
+		pf.q5[j+1] = pf.expExtGap(j) * pf.q5[j]
+		for i := 0; i < j-pf.minLoop; i++ {
+			pf.q5[j+1] += pf.q5[i] * pf.qb[i][j] * boltzmann(pf.exterior(i, j))
+		}
+	}
+	pf.q3[pf.n] = 1
+	for i := pf.n - 1; i >= 0; i-- {
+		pf.q3[i] = pf.expExtGap(i) * pf.q3[i+1]
+		for j := i + pf.minLoop + 1; j < pf.n; j++ {
+			pf.q3[i] += pf.qb[i][j] * boltzmann(pf.exterior(i, j)) * pf.q3[j+1]
+		}
+	}
+}
+
+func (pf *mccaskill) insideB(i, j int) float64 {
+	if !pf.canPair(i, j) {
+		return 0
+	}
+	q := boltzmann(pf.hairpin(i, j)) * pf.scale[j-i+1]
+	for k := i + 1; k <= i+maxLoop+1 && k < j; k++ {
+		for l := j - 1; l > k && k-i-1+j-l-1 <= maxLoop; l-- {
+			if pf.qb[k][l] > 0 {
+				q += boltzmann(pf.interior(i, j, k, l)) * pf.qb[k][l] * pf.scale[k-i+j-l]
+			}
+		}
+	}
+	multi := 0.0
+	for k := i + 2; k < j; k++ {
+		multi += pf.qm[i+1][k-1] * pf.qm1[k][j-1]
+	}
+	return q + multi*boltzmann(pf.multiClosing(i, j))*pf.scale[2]
+}
+
+func (pf *mccaskill) insideM1(i, j int) float64 {
+	q := 0.0
+	for l := i + pf.minLoop + 1; l <= j; l++ {
+		if pf.qb[i][l] > 0 {
+			q += pf.qb[i][l] * boltzmann(pf.branch(i, l)) * pf.expMLGap(l+1, j-l)
+		}
+	}
+	return q
+}
+
+func (pf *mccaskill) insideM(i, j int) float64 {
+	q := 0.0
+	for k := i; k <= j; k++ {
+		left := pf.expMLGap(i, k-i)
+		if k > i {
+			left += pf.qm[i][k-1]
+		}
+		q += left * pf.qm1[k][j]
+	}
+	return q
+}
+
+// outside pushes every outside weight back through the inside recursion,
+// longest spans first. Within a span, QM feeds QM1 which feeds QB.
+func (pf *mccaskill) outside() {
+	for d := pf.n - 1; d > pf.minLoop; d-- {
+		for i := 0; i+d < pf.n; i++ {
+			j := i + d
+			pf.outsideM(i, j)
+			pf.outsideM1(i, j)
+			pf.outsideB(i, j)
+		}
+	}
+}
+
+func (pf *mccaskill) outsideM(i, j int) {
+	o := pf.om[i][j]
+	if o == 0 {
+		return
+	}
+	for k := i; k <= j; k++ {
+		if pf.qm1[k][j] == 0 {
+			continue
+		}
+		left := pf.expMLGap(i, k-i)
+		if k > i {
+			left += pf.qm[i][k-1]
+			pf.om[i][k-1] += o * pf.qm1[k][j]
+		}
+		pf.om1[k][j] += o * left
+	}
+}
+
+func (pf *mccaskill) outsideM1(i, j int) {
+	o := pf.om1[i][j]
+	if o == 0 {
+		return
+	}
+	for l := i + pf.minLoop + 1; l <= j; l++ {
+		pf.ob[i][l] += o * boltzmann(pf.branch(i, l)) * pf.expMLGap(l+1, j-l)
+	}
+}
+
+func (pf *mccaskill) outsideB(i, j int) {
+	if pf.qb[i][j] == 0 {
+		return
+	}
+	pf.ob[i][j] += pf.q5[i] * boltzmann(pf.exterior(i, j)) * pf.q3[j+1]
+	o := pf.ob[i][j]
+	for k := i + 1; k <= i+maxLoop+1 && k < j; k++ {
+		for l := j - 1; l > k && k-i-1+j-l-1 <= maxLoop; l-- {
+			if pf.qb[k][l] > 0 {
+				pf.ob[k][l] += o * boltzmann(pf.interior(i, j, k, l)) * pf.scale[k-i+j-l]
+			}
+		}
+	}
+	w := o * boltzmann(pf.multiClosing(i, j)) * pf.scale[2]
+	for k := i + 2; k < j; k++ {
+		pf.om[i+1][k-1] += w * pf.qm1[k][j-1]
+		pf.om1[k][j-1] += w * pf.qm[i+1][k-1]
+	}
+}
+
+func (pf *mccaskill) ensemble() *Ensemble {
+	z := pf.q5[pf.n]
+	e := &Ensemble{
+		FreeEnergy: -kT * (math.Log(z) + float64(pf.n)*math.Log(pf.pfScale)) / 100,
+		Probs:      slice2DFloat(pf.n, pf.n),
+		pf:         pf,
+	}
+	for i := 0; i < pf.n; i++ {
+		for j := i + 1; j < pf.n; j++ {
+			p := pf.qb[i][j] * pf.ob[i][j] / z
+			e.Probs[i][j], e.Probs[j][i] = p, p
+		}
+	}
+	return e
+}
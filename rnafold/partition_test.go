@@ -0,0 +1,124 @@
+package rnafold
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestPartitionExhaustive compares the ensemble against a sum over every
+// structure of short sequences.
+func TestPartitionExhaustive(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for trial := 0; trial < 20; trial++ {
+		seq := randomRNA(r, 10+r.Intn(6))
+		ens, err := PartitionFunction(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		z := 0.0
+		probs := slice2DFloat(len(seq), len(seq))
+		structs := allStructures(seq, minHairpin)
+		weights := make([]float64, len(structs))
+		for k, s := range structs {
+			e, _ := EvalEnergy(seq, s)
+			w := math.Exp(-e * 100 / kT)
+			weights[k] = w
+			z += w
+			for _, p := range s.Pairs {
+				probs[p.I][p.J] += w
+			}
+		}
+		g := -kT * math.Log(z) / 100
+		if math.Abs(g-ens.FreeEnergy) > 1e-6 {
+			t.Fatalf("%v: free energy %v, exhaustive %v", seq, ens.FreeEnergy, g)
+		}
+		for i := range probs {
+			for j := i + 1; j < len(seq); j++ {
+				if math.Abs(probs[i][j]/z-ens.Probs[i][j]) > 1e-6 {
+					t.Fatalf("%v: P(%v,%v) = %v, exhaustive %v", seq, i, j, ens.Probs[i][j], probs[i][j]/z)
+				}
+			}
+		}
+		// the expected distance over every pair of structures
+		diversity := 0.0
+		for a := range structs {
+			for b := range structs {
+				d, _ := BasePairDistance(structs[a], structs[b])
+				diversity += weights[a] * weights[b] * float64(d)
+			}
+		}
+		diversity /= z * z
+		if math.Abs(diversity-ens.Diversity()) > 1e-6 {
+			t.Fatalf("%v: diversity %v, exhaustive %v", seq, ens.Diversity(), diversity)
+		}
+	}
+}
+
+func TestPartitionFixtures(t *testing.T) {
+	for _, seq := range []string{mIR1976, rNA5SP136, bCYRN1} {
+		ens, err := PartitionFunction(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mfe, _, _ := MFE(seq)
+		t.Log(ens.FreeEnergy, mfe)
+		if ens.FreeEnergy > mfe {
+			t.Fatalf("ensemble free energy %v above the MFE %v", ens.FreeEnergy, mfe)
+		}
+		for i := range ens.Probs {
+			sum := 0.0
+			for j := range ens.Probs[i] {
+				sum += ens.Probs[i][j]
+			}
+			if sum < 0 || sum > 1+1e-9 {
+				t.Fatalf("base %v paired with probability %v", i, sum)
+			}
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	seq := "GCGAUACGCUAGCAUGCAUUGCGAUAGCAUGC"
+	ens, err := PartitionFunction(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 5000
+	structs := ens.Sample(n, rand.New(rand.NewSource(6)))
+	counts := slice2DFloat(len(seq), len(seq))
+	for _, s := range structs {
+		if _, err := EvalEnergy(seq, s); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range s.Pairs {
+			counts[p.I][p.J]++
+		}
+	}
+	for i := range counts {
+		for j := i + 1; j < len(seq); j++ {
+			if math.Abs(counts[i][j]/n-ens.Probs[i][j]) > 0.03 {
+				t.Fatalf("P(%v,%v) sampled %v, expected %v", i, j, counts[i][j]/n, ens.Probs[i][j])
+			}
+		}
+	}
+	again := ens.Sample(n, rand.New(rand.NewSource(6)))
+	for k := range structs {
+		if structs[k].DotBracket() != again[k].DotBracket() {
+			t.Fatal("sampling with the same seed is not reproducible")
+		}
+	}
+	if d, want := SampleDiversity(structs), ens.Diversity(); math.Abs(d-want) > 0.05*want {
+		t.Fatalf("sample diversity %v, ensemble %v", d, want)
+	}
+	if d := SampleDiversity(structs[:1]); d != 0 {
+		t.Fatalf("diversity of one structure %v", d)
+	}
+	c := Centroid(structs)
+	t.Log(c.DotBracket())
+	for _, p := range c.Pairs {
+		if ens.Probs[p.I][p.J] < 0.4 {
+			t.Fatalf("centroid pair (%v, %v) has probability %v", p.I, p.J, ens.Probs[p.I][p.J])
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package rnafold
+
+import bio "github.com/bsjcho/bioinf"
+
+// RNA secondary structure with simple (H-type) pseudoknots using Akutsu's
+// extension of the Nussinov algorithm.
+// Input:
+// - A sequence composed of nucleotide bases (A,C,G,U)
+// Output:
+// - The max pairs (or total pair weight) over structures built from nested
+//   parts and simple pseudoknots
+// - A structure realizing it, written with extended brackets
+//
+// A simple pseudoknot over [i0,k0] splits the region into A=[i0,j0),
+// B=[j0,k) and C=[k,k0] with every pair joining A to B (left stem) or B to C
+// (right stem), each stem itself nested. For a fixed i0,
+//   F(a,b,c) = max pairs of a simple pseudoknot over [i0,a] ∪ [b,c]
+// is found by peeling bases off the inner ends a, b and c:
+//   F(a,b,c) = max( F(a-1,b,c), F(a,b+1,c), F(a,b,c-1),
+//                   F(a-1,b+1,c) + match(a,b),
+//                   F(a,b+1,c-1) + match(b,c) )
+// and PK(i0,k0) = max over j0 of F(j0-1,j0,k0). This takes O(n^4) time, so
+// the pseudoknot folder is intended for sequences of a few hundred bases.
+// Loops of the pseudoknot only hold unpaired bases.
+
+type pknot struct {
+	*nussinov
+	pk [][]int // pk[i][j] is the best simple pseudoknot over exactly [i,j]
+}
+
+// FoldPseudoknot folds seq allowing simple pseudoknots, returning the max
+// pairs and a structure with that many pairs.
+// An Options value may be given to change the pairing rules.
+func FoldPseudoknot(seq string, opts ...Options) (score int, s *Structure, err error) {
+	n, err := newNussinov(seq, opts)
+	if err != nil {
+		return 0, nil, err
+	}
+	p := &pknot{nussinov: n}
+	size := len(n.sequence)
+	p.pk = bio.Slice2D(size, size, 0)
+	for i0 := 0; i0 < size; i0++ {
+		p.fillPK(i0)
+	}
+	n.matrix = bio.Slice2D(size, size, 0)
+	for d := 1; d < size; d++ {
+		for i := 0; i+d < size; i++ {
+			n.matrix[i][i+d] = p.cell(i, i+d)
+		}
+	}
+	if size == 0 {
+		return 0, NewStructure(0, nil), nil
+	}
+	pairs := p.traceback(0, size-1, nil)
+	return n.matrix[0][size-1], NewStructure(size, pairs), nil
+}
+
+// score returns the value of the combined matrix, zero for empty intervals.
+func (p *pknot) score(i, j int) int {
+	if j <= i {
+		return 0
+	}
+	return p.matrix[i][j]
+}
+
+func (p *pknot) cell(i, j int) int {
+	best := bio.Max(p.score(i+1, j), p.score(i, j-1), p.pk[i][j])
+	if j-i > p.opts.MinLoop {
+		best = bio.Max(best, p.score(i+1, j-1)+matchScore(i, j, p.nussinov))
+	}
+	for k := i + 1; k < j; k++ {
+		best = bio.Max(best, p.score(i, k)+p.score(k+1, j))
+	}
+	return best
+}
+
+// pkTable holds F(a,b,c) for one i0 over a in [i0-1, hi], b and c in [i0, hi+1].
+type pkTable struct {
+	i0, hi int
+	w      int // width of the b and c dimensions
+	layers [][]int
+	full   bool // keep every layer (for traceback) or only the last two
+}
+
+func newPKTable(i0, hi int, full bool) *pkTable {
+	t := &pkTable{i0: i0, hi: hi, w: hi - i0 + 2, full: full}
+	count := 2
+	if full {
+		count = hi - i0 + 2
+	}
+	t.layers = make([][]int, count)
+	for k := range t.layers {
+		t.layers[k] = make([]int, t.w*t.w)
+	}
+	return t
+}
+
+func (t *pkTable) layer(a int) []int {
+	if t.full {
+		return t.layers[a-t.i0+1]
+	}
+	return t.layers[(a-t.i0+1)%2]
+}
+
+func (t *pkTable) at(a, b, c int) int {
+	if b > c {
+		return 0
+	}
+	return t.layer(a)[(b-t.i0)*t.w+c-t.i0]
+}
+
+// fillLayer computes F(a, ., .), the layer F(a-1, ., .) being complete.
+func (p *pknot) fillLayer(t *pkTable, a int) {
+	l := t.layer(a)
+	for b := t.hi; b > a; b-- {
+		for c := b; c <= t.hi; c++ {
+			l[(b-t.i0)*t.w+c-t.i0] = p.pkCell(t, a, b, c)
+		}
+	}
+}
+
+func (p *pknot) pkCell(t *pkTable, a, b, c int) int {
+	best := bio.Max(t.at(a, b+1, c), t.at(a, b, c-1))
+	if c-b > p.opts.MinLoop {
+		if w := matchScore(b, c, p.nussinov); w > 0 {
+			best = bio.Max(best, t.at(a, b+1, c-1)+w)
+		}
+	}
+	if a >= t.i0 {
+		best = bio.Max(best, t.at(a-1, b, c))
+		if b-a > p.opts.MinLoop {
+			if w := matchScore(a, b, p.nussinov); w > 0 {
+				best = bio.Max(best, t.at(a-1, b+1, c)+w)
+			}
+		}
+	}
+	return best
+}
+
+// fillPK computes pk[i0][k0] for every k0.
+func (p *pknot) fillPK(i0 int) {
+	hi := len(p.sequence) - 1
+	t := newPKTable(i0, hi, false)
+	for a := i0 - 1; a < hi; a++ {
+		p.fillLayer(t, a)
+		if a < i0 {
+			continue
+		}
+		for k0 := a + 1; k0 <= hi; k0++ {
+			p.pk[i0][k0] = bio.Max(p.pk[i0][k0], t.at(a, a+1, k0))
+		}
+	}
+}
+
+func (p *pknot) traceback(i, j int, pairs []Pair) []Pair {
+	if j <= i {
+		return pairs
+	}
+	s := p.score(i, j)
+	switch {
+	case s == 0:
+		return pairs
+	case s == p.score(i+1, j):
+		return p.traceback(i+1, j, pairs)
+	case s == p.score(i, j-1):
+		return p.traceback(i, j-1, pairs)
+	case j-i > p.opts.MinLoop && matchScore(i, j, p.nussinov) > 0 &&
+		s == p.score(i+1, j-1)+matchScore(i, j, p.nussinov):
+		pairs = append(pairs, Pair{I: i, J: j})
+		return p.traceback(i+1, j-1, pairs)
+	}
+	for k := i + 1; k < j; k++ {
+		if s == p.score(i, k)+p.score(k+1, j) {
+			pairs = p.traceback(i, k, pairs)
+			return p.traceback(k+1, j, pairs)
+		}
+	}
+	return p.tracePK(i, j, pairs)
+}
+
+// tracePK recovers the pairs of the best simple pseudoknot over [i0, k0].
+func (p *pknot) tracePK(i0, k0 int, pairs []Pair) []Pair {
+	t := newPKTable(i0, k0, true)
+	for a := i0 - 1; a < k0; a++ {
+		p.fillLayer(t, a)
+	}
+	a, b, c := -1, 0, 0
+	for j0 := i0 + 1; j0 <= k0; j0++ {
+		if t.at(j0-1, j0, k0) == p.pk[i0][k0] {
+			a, b, c = j0-1, j0, k0
+			break
+		}
+	}
+	for b <= c {
+		v := t.at(a, b, c)
+		switch {
+		case v == 0:
+			return pairs
+		case v == t.at(a, b+1, c):
+			b++
+		case v == t.at(a, b, c-1):
+			c--
+		case c-b > p.opts.MinLoop && matchScore(b, c, p.nussinov) > 0 &&
+			v == t.at(a, b+1, c-1)+matchScore(b, c, p.nussinov):
+			pairs = append(pairs, Pair{I: b, J: c})
+			b, c = b+1, c-1
+		case a >= i0 && v == t.at(a-1, b, c):
+			a--
+		default:
+			pairs = append(pairs, Pair{I: a, J: b})
+			a, b = a-1, b+1
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,54 @@
+package rnafold
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestFoldPseudoknot(t *testing.T) {
+	// GGGG pairs with CCCC and UUUU with AAAA across it
+	seq := "GGGGAAAUUUUCCCCAAAAAAA"
+	score, s, err := FoldPseudoknot(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(score, s.DotBracket())
+	if score != 8 || !strings.Contains(s.DotBracket(), "[") {
+		t.Fatalf("pseudoknot score %v %v, want 8 with crossing pairs", score, s.DotBracket())
+	}
+	if nested := mustScore(t, FoldScore, seq); nested >= score {
+		t.Fatalf("nested score %v not below pseudoknot score %v", nested, score)
+	}
+	parsed, err := ParseDotBracket(s.DotBracket())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := BasePairDistance(s, parsed); d != 0 {
+		t.Fatalf("extended brackets %v did not round trip", s.DotBracket())
+	}
+}
+
+func TestFoldPseudoknotRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(10))
+	for trial := 0; trial < 30; trial++ {
+		seq := randomRNA(r, 5+r.Intn(40))
+		score, s, err := FoldPseudoknot(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nested := mustScore(t, FoldScore, seq); score < nested {
+			t.Fatalf("%v: pseudoknot score %v below nested score %v", seq, score, nested)
+		}
+		if len(s.Pairs) != score {
+			t.Fatalf("%v: score %v with %v pairs", seq, score, len(s.Pairs))
+		}
+		used := map[int]bool{}
+		for _, p := range s.Pairs {
+			if used[p.I] || used[p.J] || p.J-p.I < 2 || pairScore(seq[p.I], seq[p.J]) == 0 {
+				t.Fatalf("%v: invalid pair %v in %v", seq, p, s.DotBracket())
+			}
+			used[p.I], used[p.J] = true, true
+		}
+	}
+}
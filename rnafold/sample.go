@@ -0,0 +1,174 @@
+package rnafold
+
+import "math/rand"
+
+// Stochastic sampling of secondary structures from the Boltzmann ensemble
+// (Ding & Lawrence). Each structure is drawn by a random traceback through
+// the partition function tables, choosing every decomposition with
+// probability proportional to its share of the enclosing table entry.
+
+// Sample draws n structures from the ensemble with probability proportional
+// to their Boltzmann weight. Results are reproducible for a given r.
+func (e *Ensemble) Sample(n int, r *rand.Rand) []*Structure {
+	structs := make([]*Structure, n)
+	for k := range structs {
+		structs[k] = e.pf.sample(r)
+	}
+	return structs
+}
+
+func (pf *mccaskill) sample(r *rand.Rand) *Structure {
+	var pairs []Pair
+	pending := []traceItem{{'W', 0, pf.n - 1}}
+	push := func(table byte, i, j int) {
+		pending = append(pending, traceItem{table, i, j})
+	}
+	for len(pending) > 0 {
+		t := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		i, j := t.i, t.j
+		switch t.table {
+		case 'W':
+			if j < 0 {
+				continue
+			}
+			c := newChooser(r, pf.q5[j+1])
+			if c.choose(pf.q5[j] * pf.expExtGap(j)) {
+				push('W', 0, j-1)
+				continue
+			}
+			for i := 0; i < j-pf.minLoop; i++ {
+				if c.choose(pf.q5[i] * pf.qb[i][j] * boltzmann(pf.exterior(i, j))) {
+					push('W', 0, i-1)
+					push('V', i, j)
+					break
+				}
+			}
+		case 'V':
+			pairs = append(pairs, Pair{I: i, J: j})
+			pf.sampleB(i, j, newChooser(r, pf.qb[i][j]), push)
+		case 'M':
+			c := newChooser(r, pf.qm[i][j])
+			for k := i; k <= j && !c.done; k++ {
+				if c.choose(pf.expMLGap(i, k-i) * pf.qm1[k][j]) {
+					push('1', k, j)
+				} else if k > i && c.choose(pf.qm[i][k-1]*pf.qm1[k][j]) {
+					push('M', i, k-1)
+					push('1', k, j)
+				}
+			}
+		case '1':
+			c := newChooser(r, pf.qm1[i][j])
+			for l := i + pf.minLoop + 1; l <= j; l++ {
+				if c.choose(pf.qb[i][l] * boltzmann(pf.branch(i, l)) * pf.expMLGap(l+1, j-l)) {
+					push('V', i, l)
+					break
+				}
+			}
+		}
+	}
+	return NewStructure(pf.n, pairs)
+}
+
+// sampleB picks the loop closed by (i, j).
+func (pf *mccaskill) sampleB(i, j int, c *chooser, push func(byte, int, int)) {
+	if c.choose(boltzmann(pf.hairpin(i, j)) * pf.scale[j-i+1]) {
+		return
+	}
+	for k := i + 1; k <= i+maxLoop+1 && k < j; k++ {
+		for l := j - 1; l > k && k-i-1+j-l-1 <= maxLoop; l-- {
+			if pf.qb[k][l] > 0 &&
+				c.choose(boltzmann(pf.interior(i, j, k, l))*pf.qb[k][l]*pf.scale[k-i+j-l]) {
+				push('V', k, l)
+				return
+			}
+		}
+	}
+	w := boltzmann(pf.multiClosing(i, j)) * pf.scale[2]
+	for k := i + 2; k < j; k++ {
+		if c.choose(w * pf.qm[i+1][k-1] * pf.qm1[k][j-1]) {
+			push('M', i+1, k-1)
+			push('1', k, j-1)
+			return
+		}
+	}
+}
+
+// chooser walks the terms of a sum, accepting the first term at which the
+// running total passes a uniformly drawn fraction of the sum.
+type chooser struct {
+	target, total float64
+	done          bool
+}
+
+func newChooser(r *rand.Rand, sum float64) *chooser {
+	// shave the target so rounding in the running total cannot miss it
+	return &chooser{target: r.Float64() * sum * (1 - 1e-12)}
+}
+
+func (c *chooser) choose(w float64) bool {
+	if c.done || w <= 0 {
+		return false
+	}
+	c.total += w
+	c.done = c.total >= c.target
+	return c.done
+}
+
+// Diversity returns the ensemble diversity: the expected base pair distance
+// between two structures drawn independently from the ensemble,
+// 2 Σ p(1-p) over the pair probabilities p.
+func (e *Ensemble) Diversity() float64 {
+	d := 0.0
+	for i := range e.Probs {
+		for j := i + 1; j < len(e.Probs); j++ {
+			p := e.Probs[i][j]
+			d += p * (1 - p)
+		}
+	}
+	return 2 * d
+}
+
+// SampleDiversity returns the mean base pair distance between two of
+// structs, which estimates Ensemble.Diversity for a Boltzmann sample.
+// It is zero for fewer than two structures.
+func SampleDiversity(structs []*Structure) float64 {
+	n := len(structs)
+	if n < 2 {
+		return 0
+	}
+	counts := map[Pair]int{}
+	for _, s := range structs {
+		for _, p := range s.Pairs {
+			counts[p]++
+		}
+	}
+	// a pair found in c structures is in exactly one of c(n-c) of the
+	// n(n-1)/2 pairs of structures
+	d := 0.0
+	for _, c := range counts {
+		d += float64(c * (n - c))
+	}
+	return 2 * d / float64(n*(n-1))
+}
+
+// Centroid returns the structure made of the pairs present in more than
+// half of structs, which are all assumed to be over the same sequence.
+func Centroid(structs []*Structure) *Structure {
+	if len(structs) == 0 {
+		return NewStructure(0, nil)
+	}
+	counts := map[Pair]int{}
+	for _, s := range structs {
+		for _, p := range s.Pairs {
+			counts[p]++
+		}
+	}
+	var pairs []Pair
+	for p, c := range counts {
+		if 2*c > len(structs) {
+			pairs = append(pairs, p)
+		}
+	}
+	return NewStructure(structs[0].Length, pairs)
+}
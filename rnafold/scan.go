@@ -0,0 +1,221 @@
+package rnafold
+
+import (
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// Local folding of long sequences by scanning a window along them.
+// Input:
+// - A sequence composed of nucleotide bases (A,C,G,U)
+// - A window size W and a maximum pair span L (j-i <= L)
+// Output:
+// - The optimal structure of every window of W bases
+//
+// The tables are only filled for intervals of at most L bases, one row at a
+// time from the 3' end, so they take O(n·L) memory. Once the rows of a
+// window are filled its optimum is found by an exterior loop recursion over
+// the window alone, and the window is reported before the next row is
+// computed. Windows are therefore reported from the 3' end of the sequence.
+
+// Window is the optimal structure of one window of a scan.
+type Window struct {
+	// Start is the position of the first base of the window in the sequence.
+	Start int
+	// Score is the max pairs (or total pair weight), set by Scan.
+	Score int
+	// Energy is the minimum free energy in kcal/mol, set by ScanMFE.
+	Energy float64
+	// Structure is over the window: pair positions are relative to Start.
+	Structure *Structure
+}
+
+// Scan folds every window of seq with the Nussinov algorithm, calling fn
+// with each window's optimum as soon as it is known. The scan stops when fn
+// returns false. Options.Constraints are not supported.
+func Scan(seq string, window, span int, fn func(*Window) bool, opts ...Options) error {
+	n, err := newNussinov(seq, opts)
+	if err != nil {
+		return err
+	}
+	size := len(n.sequence)
+	if window, span, err = scanBounds(size, window, span, n.opts); err != nil {
+		return err
+	}
+	l := &localNussinov{nussinov: n, s: newBand(size, span, 0), c: newBand(size, span, -1)}
+	for i := size - 1; i >= 0; i-- {
+		l.fillRow(i)
+		if i+window <= size && !fn(l.window(i, window)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ScanMFE is Scan with the nearest-neighbor energy model, each window
+// holding a minimum free energy structure.
+func ScanMFE(seq string, window, span int, fn func(*Window) bool, opts ...Options) error {
+	m, err := newEnergyModel(seq, opts)
+	if err != nil {
+		return err
+	}
+	size := len(m.seq)
+	if window, span, err = scanBounds(size, window, span, m.opts); err != nil {
+		return err
+	}
+	z := &localZuker{zuker: newBandedZuker(m, span), c: newBand(size, span, inf)}
+	for i := size - 1; i >= 0; i-- {
+		z.fillRow(i)
+		if i+window <= size && !fn(z.window(i, window)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// scanBounds checks the scan parameters, clipping the window to the
+// sequence and the span to the window.
+func scanBounds(size, window, span int, o Options) (int, int, error) {
+	if window < 1 || span < 1 {
+		return 0, 0, fmt.Errorf("rnafold: invalid window %d or span %d", window, span)
+	}
+	if o.Constraints != nil {
+		return 0, 0, fmt.Errorf("rnafold: constraints are not supported by scans")
+	}
+	window = bio.Min(window, size)
+	span = bio.Min(span, window-1)
+	return window, bio.Max(span, 0), nil
+}
+
+type localNussinov struct {
+	*nussinov
+	s *band // s.at(i, j) is S(i,j)
+	c *band // c.at(i, j) is the best score with i paired to j, or -1
+}
+
+func (l *localNussinov) fillRow(i int) {
+	for j := i + l.opts.MinLoop + 1; j < len(l.sequence) && j-i <= l.s.width; j++ {
+		best := bio.Max(l.s.at(i+1, j), l.s.at(i, j-1))
+		if w := matchScore(i, j, l.nussinov); w > 0 {
+			l.c.set(i, j, l.s.at(i+1, j-1)+w)
+			best = bio.Max(best, l.c.at(i, j))
+		}
+		for k := i + 1; k < j; k++ {
+			best = bio.Max(best, l.s.at(i, k)+l.s.at(k+1, j))
+		}
+		l.s.set(i, j, best)
+	}
+}
+
+// window finds the best structure over [start, start+size) from the pairs
+// (k, j) closing its exterior loop.
+func (l *localNussinov) window(start, size int) *Window {
+	f := make([]int, size+1) // f[j-start+1] covers [start, j]
+	for j := start; j < start+size; j++ {
+		best := f[j-start]
+		for k := bio.Max(start, j-l.s.width); k < j-l.opts.MinLoop; k++ {
+			if c := l.c.at(k, j); c >= 0 {
+				best = bio.Max(best, f[k-start]+c)
+			}
+		}
+		f[j-start+1] = best
+	}
+	var pairs []Pair
+	for j := start + size - 1; j >= start; {
+		if f[j-start+1] == f[j-start] {
+			j--
+			continue
+		}
+		for k := bio.Max(start, j-l.s.width); k < j-l.opts.MinLoop; k++ {
+			if c := l.c.at(k, j); c >= 0 && f[j-start+1] == f[k-start]+c {
+				pairs = append(pairs, Pair{I: k, J: j})
+				pairs = l.traceback(k+1, j-1, pairs)
+				j = k - 1
+				break
+			}
+		}
+	}
+	return &Window{Start: start, Score: f[size], Structure: relative(start, size, pairs)}
+}
+
+func (l *localNussinov) traceback(i, j int, pairs []Pair) []Pair {
+	if j-i <= l.opts.MinLoop {
+		return pairs
+	}
+	score := l.s.at(i, j)
+	switch {
+	case score == l.s.at(i+1, j):
+		return l.traceback(i+1, j, pairs)
+	case score == l.s.at(i, j-1):
+		return l.traceback(i, j-1, pairs)
+	case matchScore(i, j, l.nussinov) > 0 &&
+		score == l.s.at(i+1, j-1)+matchScore(i, j, l.nussinov):
+		pairs = append(pairs, Pair{I: i, J: j})
+		return l.traceback(i+1, j-1, pairs)
+	}
+	for k := i + 1; k < j; k++ {
+		if score == l.s.at(i, k)+l.s.at(k+1, j) {
+			pairs = l.traceback(i, k, pairs)
+			return l.traceback(k+1, j, pairs)
+		}
+	}
+	return pairs
+}
+
+type localZuker struct {
+	*zuker
+	c *band // c.at(i, j) is the energy of (i, j) closing an exterior loop
+}
+
+func (z *localZuker) fillRow(i int) {
+	z.zuker.fillRow(i, false)
+	for j := i + z.minLoop + 1; j < z.n && j-i <= z.c.width; j++ {
+		if v := z.v.at(i, j); v < inf {
+			z.c.set(i, j, v+z.exterior(i, j))
+		}
+	}
+}
+
+// window finds the minimum free energy structure over [start, start+size)
+// from the pairs (k, j) closing its exterior loop.
+func (z *localZuker) window(start, size int) *Window {
+	f := make([]int, size+1) // f[j-start+1] covers [start, j]
+	for j := start; j < start+size; j++ {
+		best := f[j-start]
+		for k := bio.Max(start, j-z.v.width); k < j-z.minLoop; k++ {
+			if c := z.c.at(k, j); c < inf {
+				best = bio.Min(best, f[k-start]+c)
+			}
+		}
+		f[j-start+1] = best
+	}
+	var items []traceItem
+	for j := start + size - 1; j >= start; {
+		if f[j-start+1] == f[j-start] {
+			j--
+			continue
+		}
+		for k := bio.Max(start, j-z.v.width); k < j-z.minLoop; k++ {
+			if c := z.c.at(k, j); c < inf && f[j-start+1] == f[k-start]+c {
+				items = append(items, traceItem{'V', k, j})
+				j = k - 1
+				break
+			}
+		}
+	}
+	return &Window{
+		Start:     start,
+		Energy:    float64(f[size]) / 100,
+		Structure: relative(start, size, z.trace(items...)),
+	}
+}
+
+// relative returns a structure over [start, start+size) of the given pairs.
+func relative(start, size int, pairs []Pair) *Structure {
+	for k := range pairs {
+		pairs[k].I -= start
+		pairs[k].J -= start
+	}
+	return NewStructure(size, pairs)
+}
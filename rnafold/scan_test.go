@@ -0,0 +1,89 @@
+package rnafold
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	r := rand.New(rand.NewSource(14))
+	seq := randomRNA(r, 120)
+	const size = 40
+	next := len(seq) - size
+	err := Scan(seq, size, size-1, func(w *Window) bool {
+		if w.Start != next {
+			t.Fatalf("window %v reported, expected %v", w.Start, next)
+		}
+		next--
+		sub := seq[w.Start : w.Start+size]
+		if score := mustScore(t, FoldScore, sub); w.Score != score || len(w.Structure.Pairs) != score {
+			t.Fatalf("window %v: score %v (%v pairs), FoldScore %v",
+				w.Start, w.Score, len(w.Structure.Pairs), score)
+		}
+		return true
+	})
+	if err != nil || next != -1 {
+		t.Fatalf("scan stopped at %v: %v", next, err)
+	}
+
+	const span = 10
+	calls := 0
+	Scan(seq, size, span, func(w *Window) bool {
+		for _, p := range w.Structure.Pairs {
+			if p.J-p.I > span {
+				t.Fatalf("window %v: pair %v spans more than %v", w.Start, p, span)
+			}
+		}
+		calls++
+		return calls < 5
+	})
+	if calls != 5 {
+		t.Fatalf("scan did not stop: %v calls", calls)
+	}
+}
+
+func TestScanMFE(t *testing.T) {
+	r := rand.New(rand.NewSource(15))
+	seq := randomRNA(r, 90)
+	const size = 35
+	err := ScanMFE(seq, size, size-1, func(w *Window) bool {
+		sub := seq[w.Start : w.Start+size]
+		e, _, _ := MFE(sub)
+		eval, err := EvalEnergy(sub, w.Structure)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(w.Energy-e) > 1e-9 || math.Abs(eval-e) > 1e-9 {
+			t.Fatalf("window %v: energy %v (structure %v), MFE %v", w.Start, w.Energy, eval, e)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a whole sequence window with a bounded span gives up the long pairs
+	seq = "GGGGAAACCCCAAAAAAAAAAAAAAAAAAAAAAGGGGAAACCCC"
+	ScanMFE(seq, len(seq), 12, func(w *Window) bool {
+		if db := w.Structure.DotBracket(); db != "((((...))))......................((((...))))" {
+			t.Fatalf("structure %v", db)
+		}
+		return true
+	})
+	if err := ScanMFE(seq, 0, 10, func(*Window) bool { return true }); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func BenchmarkScan10k(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Scan(rna10k, 200, 150, func(*Window) bool { return true })
+	}
+}
+
+func BenchmarkScanMFE10k(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ScanMFE(rna10k, 200, 150, func(*Window) bool { return true })
+	}
+}
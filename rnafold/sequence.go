@@ -0,0 +1,47 @@
+package rnafold
+
+import (
+	"fmt"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// Normalize converts seq to the upper case RNA alphabet used by this
+// package, reading T as U. Any other character is an error.
+func Normalize(seq string) (string, error) {
+	rna := []byte(seq)
+	for i, c := range rna {
+		switch c {
+		case 'A', 'C', 'G', 'U':
+		case 'a', 'c', 'g', 'u':
+			rna[i] = c - 'a' + 'A'
+		case 'T', 't':
+			rna[i] = 'U'
+		default:
+			return "", fmt.Errorf("rnafold: invalid nucleotide %q at position %d", c, i)
+		}
+	}
+	return string(rna), nil
+}
+
+// SequenceToRNA converts a bio.Sequence of DNA or RNA to an RNA string,
+// reading T as U.
+// Gaps are an error.
+func SequenceToRNA(seq *bio.Sequence) (string, error) {
+	rna := make([]byte, len(seq.Bases))
+	for i, b := range seq.Bases {
+		switch b {
+		case bio.A:
+			rna[i] = 'A'
+		case bio.C:
+			rna[i] = 'C'
+		case bio.G:
+			rna[i] = 'G'
+		case bio.T, bio.U:
+			rna[i] = 'U'
+		default:
+			return "", fmt.Errorf("rnafold: invalid base %v at position %d", b, i)
+		}
+	}
+	return string(rna), nil
+}
@@ -0,0 +1,75 @@
+package rnafold
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestShapeHairpin(t *testing.T) {
+	seq := "GGGGAAACCCC"
+	reactivity := make([]float64, len(seq))
+	e, s, err := MFE(seq, Options{Shape: NewShape(reactivity)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// three stacks of four bases, each unreactive base -0.8
+	if math.Abs(e-(-4.5-12*0.8)) > 1e-9 || s.DotBracket() != "((((...))))" {
+		t.Fatalf("MFE %v %v", e, s.DotBracket())
+	}
+	// highly reactive bases cannot stack
+	for i := range reactivity {
+		reactivity[i] = 10
+	}
+	e, s, _ = MFE(seq, Options{Shape: NewShape(reactivity)})
+	if e != 0 || len(s.Pairs) != 0 {
+		t.Fatalf("reactive MFE %v %v", e, s.DotBracket())
+	}
+	if _, _, err := MFE(seq, Options{Shape: NewShape(reactivity[1:])}); err == nil {
+		t.Fatal("expected a length error")
+	}
+}
+
+func TestShapeExhaustive(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	for trial := 0; trial < 20; trial++ {
+		seq := randomRNA(r, 10+r.Intn(6))
+		reactivity := make([]float64, len(seq))
+		for i := range reactivity {
+			reactivity[i] = r.Float64()*2 - 0.2
+		}
+		opts := Options{Shape: NewShape(reactivity)}
+		e, _, err := MFE(seq, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		best := 0.0
+		for _, s := range allStructures(seq, minHairpin) {
+			eval, _ := EvalEnergy(seq, s, opts)
+			best = math.Min(best, eval)
+		}
+		if math.Abs(e-best) > 1e-9 {
+			t.Fatalf("%v: MFE %v, exhaustive minimum %v", seq, e, best)
+		}
+	}
+}
+
+func TestReadShape(t *testing.T) {
+	s, err := ReadShape(strings.NewReader("1 0.5\n3 -999\n\n4 1.25\n"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0.5, -1, -999, 1.25, -1}
+	for i, v := range want {
+		if s.Reactivity[i] != v {
+			t.Fatalf("reactivity %v, want %v", s.Reactivity, want)
+		}
+	}
+	if s.Slope != DefaultShapeSlope || s.Intercept != DefaultShapeIntercept {
+		t.Fatalf("parameters %v %v", s.Slope, s.Intercept)
+	}
+	if _, err := ReadShape(strings.NewReader("6 0.1\n"), 5); err == nil {
+		t.Fatal("expected a range error")
+	}
+}
@@ -0,0 +1,107 @@
+package rnafold
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Pair is a base pair between the (zero based) positions I and J, I < J.
+type Pair struct {
+	I, J int
+}
+
+// Structure is an RNA secondary structure over a sequence of Length bases.
+type Structure struct {
+	Length int
+	Pairs  []Pair
+}
+
+// NewStructure is a Structure constructor. Pairs are sorted by I.
+func NewStructure(length int, pairs []Pair) *Structure {
+	s := &Structure{Length: length, Pairs: pairs}
+	sort.Slice(s.Pairs, func(a, b int) bool { return s.Pairs[a].I < s.Pairs[b].I })
+	return s
+}
+
+// DotBracket returns the structure in dot-bracket notation, ie. "((..))..".
+// Crossing pairs (pseudoknots) are written with [], {}, <> and then
+// matching letters (Aa, Bb, ...), each pair taking the first bracket type
+// none of whose pairs it crosses.
+func (s *Structure) DotBracket() string {
+	db := make([]byte, s.Length)
+	for i := range db {
+		db[i] = '.'
+	}
+	var levels [][]Pair
+	for _, p := range s.Pairs {
+		level := 0
+		for ; level < len(levels); level++ {
+			if !crossesAny(p, levels[level]) {
+				break
+			}
+		}
+		if level == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[level] = append(levels[level], p)
+		open, close := bracketSymbols(level)
+		db[p.I] = open
+		db[p.J] = close
+	}
+	return string(db)
+}
+
+// pairTable returns the partner of every base, -1 if unpaired. The pairs
+// of s must be in range, pair every base at most once and not cross.
+func pairTable(s *Structure) ([]int, error) {
+	pt := make([]int, s.Length)
+	for i := range pt {
+		pt[i] = -1
+	}
+	for _, p := range s.Pairs {
+		if p.I < 0 || p.I >= p.J || p.J >= s.Length {
+			return nil, fmt.Errorf("rnafold: invalid pair (%d, %d)", p.I, p.J)
+		}
+		if pt[p.I] >= 0 || pt[p.J] >= 0 {
+			return nil, fmt.Errorf("rnafold: pair (%d, %d) shares a base with another pair", p.I, p.J)
+		}
+		pt[p.I], pt[p.J] = p.J, p.I
+	}
+	var open []int
+	for i, j := range pt {
+		switch {
+		case j > i:
+			open = append(open, i)
+		case j >= 0:
+			if open[len(open)-1] != j {
+				return nil, fmt.Errorf("rnafold: pair (%d, %d) crosses another pair", j, i)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	return pt, nil
+}
+
+// crosses reports whether two pairs form a pseudoknot.
+func crosses(p, q Pair) bool {
+	return p.I < q.I && q.I < p.J && p.J < q.J ||
+		q.I < p.I && p.I < q.J && q.J < p.J
+}
+
+func crossesAny(p Pair, pairs []Pair) bool {
+	for _, q := range pairs {
+		if crosses(p, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// bracketSymbols returns the symbols used for pairs of the given level.
+func bracketSymbols(level int) (open, close byte) {
+	if level < len(brackets) {
+		return brackets[level].open, brackets[level].close
+	}
+	letter := byte('A' + (level-len(brackets))%26)
+	return letter, letter - 'A' + 'a'
+}
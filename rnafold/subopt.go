@@ -0,0 +1,268 @@
+package rnafold
+
+import (
+	"container/heap"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// Enumeration of suboptimal secondary structures (Wuchty et al.).
+// Input:
+// - A sequence composed of nucleotide bases (A,C,G,U)
+// - A band delta above the optimum
+// Output:
+// - Every structure scoring within delta of the optimum, best first
+//
+// A partial structure is a set of pairs plus table entries still to be
+// decomposed. Its bound is the score of its pairs plus the optimum of every
+// pending entry, which some completion reaches exactly. Partials are
+// expanded in order of their bound, replacing a pending entry by each of its
+// decompositions that stays within the band, so complete structures come
+// out best first and every one exactly once, the grammars being unambiguous.
+// Nussinov scores are negated so that both folders minimize.
+//
+// For Nussinov the unambiguous grammar is
+//   S(i,j) = min( S(i+1,j), min_{i<k<=j} -match(i,k) + S(i+1,k-1) + S(k+1,j) )
+// while the energy folder uses the V, WM, WM1 and W5 tables of MFE.
+
+// Subopt iterates over suboptimal structures. Call Next before each
+// structure, as for a bufio.Scanner.
+type Subopt struct {
+	g       grammar
+	n       int
+	limit   int // largest bound in the band
+	queue   partials
+	count   int
+	current *partial
+}
+
+// grammar decomposes the table entries of a folding algorithm.
+type grammar interface {
+	// optimum returns the best score of an entry.
+	optimum(t traceItem) int
+	// expand calls fn with every decomposition of an entry: the score it
+	// adds, the pair it forms (if any) and the entries left to decompose.
+	expand(t traceItem, fn func(e int, p *Pair, items ...traceItem))
+}
+
+type partial struct {
+	bound   int
+	order   int // insertion order, to break ties deterministically
+	pairs   *pairNode
+	pending []traceItem
+}
+
+type pairNode struct {
+	p    Pair
+	next *pairNode
+}
+
+// Suboptimal enumerates the Nussinov structures of seq with at least the
+// optimal number of pairs (or pair weight) minus delta.
+func Suboptimal(seq string, delta int, opts ...Options) (*Subopt, error) {
+	n, err := newNussinov(seq, opts)
+	if err != nil {
+		return nil, err
+	}
+	size := len(n.sequence)
+	n.matrix = bio.Slice2D(size, size, 0)
+	fill(n, 1)
+	best, err := n.result()
+	if err != nil {
+		return nil, err
+	}
+	return newSubopt(nussinovGrammar{n}, size, traceItem{'S', 0, size - 1}, -best+delta), nil
+}
+
+// SuboptimalMFE enumerates the structures of seq with a free energy at
+// most delta kcal/mol above the minimum.
+func SuboptimalMFE(seq string, delta float64, opts ...Options) (*Subopt, error) {
+	m, err := newEnergyModel(seq, opts)
+	if err != nil {
+		return nil, err
+	}
+	z := newZuker(m)
+	z.fill()
+	if z.w5[z.n] >= inf {
+		return nil, errInfeasible
+	}
+	limit := z.w5[z.n] + int(delta*100+0.5)
+	return newSubopt(zukerGrammar{z}, z.n, traceItem{'W', 0, z.n - 1}, limit), nil
+}
+
+func newSubopt(g grammar, n int, start traceItem, limit int) *Subopt {
+	s := &Subopt{g: g, n: n, limit: limit}
+	s.push(&partial{bound: g.optimum(start), pending: []traceItem{start}})
+	return s
+}
+
+func (s *Subopt) push(p *partial) {
+	p.order = s.count
+	s.count++
+	heap.Push(&s.queue, p)
+}
+
+// Next advances to the next structure, returning false when the band is
+// exhausted.
+func (s *Subopt) Next() bool {
+	for s.queue.Len() > 0 {
+		p := heap.Pop(&s.queue).(*partial)
+		if len(p.pending) == 0 {
+			s.current = p
+			return true
+		}
+		t := p.pending[len(p.pending)-1]
+		rest := p.pending[:len(p.pending)-1]
+		base := p.bound - s.g.optimum(t)
+		s.g.expand(t, func(e int, pair *Pair, items ...traceItem) {
+			bound := base + e
+			for _, it := range items {
+				bound += s.g.optimum(it)
+			}
+			if bound > s.limit {
+				return
+			}
+			q := &partial{bound: bound, pairs: p.pairs}
+			if pair != nil {
+				q.pairs = &pairNode{p: *pair, next: p.pairs}
+			}
+			q.pending = append(append(make([]traceItem, 0, len(rest)+len(items)), rest...), items...)
+			s.push(q)
+		})
+	}
+	s.current = nil
+	return false
+}
+
+// Structure returns the current structure.
+func (s *Subopt) Structure() *Structure {
+	var pairs []Pair
+	for node := s.current.pairs; node != nil; node = node.next {
+		pairs = append(pairs, node.p)
+	}
+	return NewStructure(s.n, pairs)
+}
+
+// Score returns the number of pairs (or pair weight) of the current
+// structure of Suboptimal.
+func (s *Subopt) Score() int {
+	return -s.current.bound
+}
+
+// Energy returns the free energy in kcal/mol of the current structure of
+// SuboptimalMFE.
+func (s *Subopt) Energy() float64 {
+	return float64(s.current.bound) / 100
+}
+
+// partials is a min-heap of partial structures.
+type partials []*partial
+
+func (q partials) Len() int { return len(q) }
+func (q partials) Less(a, b int) bool {
+	if q[a].bound != q[b].bound {
+		return q[a].bound < q[b].bound
+	}
+	return q[a].order < q[b].order
+}
+func (q partials) Swap(a, b int)       { q[a], q[b] = q[b], q[a] }
+func (q *partials) Push(x interface{}) { *q = append(*q, x.(*partial)) }
+func (q *partials) Pop() interface{} {
+	old := *q
+	p := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return p
+}
+
+type nussinovGrammar struct {
+	n *nussinov
+}
+
+func (g nussinovGrammar) optimum(t traceItem) int {
+	return -foldScore(t.i, t.j, g.n)
+}
+
+func (g nussinovGrammar) expand(t traceItem, fn func(int, *Pair, ...traceItem)) {
+	i, j := t.i, t.j
+	if j < i {
+		fn(0, nil)
+		return
+	}
+	if g.n.cons.canSkip(i, i) {
+		fn(0, nil, traceItem{'S', i + 1, j})
+	}
+	for k := i + g.n.opts.MinLoop + 1; k <= j; k++ {
+		if w := matchScore(i, k, g.n); w > 0 {
+			fn(-w, &Pair{I: i, J: k}, traceItem{'S', i + 1, k - 1}, traceItem{'S', k + 1, j})
+		}
+	}
+}
+
+type zukerGrammar struct {
+	z *zuker
+}
+
+func (g zukerGrammar) optimum(t traceItem) int {
+	z := g.z
+	switch t.table {
+	case 'W':
+		return z.w5[t.j+1]
+	case 'V':
+		return z.v.at(t.i, t.j)
+	case 'M':
+		return z.wm.at(t.i, t.j)
+	}
+	return z.wm1.at(t.i, t.j)
+}
+
+func (g zukerGrammar) expand(t traceItem, fn func(int, *Pair, ...traceItem)) {
+	z, i, j := g.z, t.i, t.j
+	switch t.table {
+	case 'W':
+		if j < 0 {
+			fn(0, nil)
+			return
+		}
+		if z.cons.canSkip(j, j) {
+			fn(0, nil, traceItem{'W', 0, j - 1})
+		}
+		for i := bio.Max(0, j-z.v.width); i < j-z.minLoop; i++ {
+			if z.v.at(i, j) < inf {
+				fn(z.exterior(i, j), nil, traceItem{'W', 0, i - 1}, traceItem{'V', i, j})
+			}
+		}
+	case 'V':
+		p := &Pair{I: i, J: j}
+		if e := z.hairpin(i, j); e < inf {
+			fn(e, p)
+		}
+		z.interiorLoops(i, j, func(k, l int) {
+			if e := z.interior(i, j, k, l); e < inf {
+				fn(e, p, traceItem{'V', k, l})
+			}
+		})
+		for k := i + 2; k < j; k++ {
+			if z.wm.at(i+1, k-1) < inf && z.wm1.at(k, j-1) < inf {
+				fn(z.multiClosing(i, j), p, traceItem{'M', i + 1, k - 1}, traceItem{'1', k, j - 1})
+			}
+		}
+	case '1':
+		for l := i + z.minLoop + 1; l <= j; l++ {
+			if gap := z.mlGap(l+1, j-l); z.v.at(i, l) < inf && gap < inf {
+				fn(z.branch(i, l)+gap, nil, traceItem{'V', i, l})
+			}
+		}
+	case 'M':
+		for k := i; k <= j; k++ {
+			if z.wm1.at(k, j) >= inf {
+				continue
+			}
+			if gap := z.mlGap(i, k-i); gap < inf {
+				fn(gap, nil, traceItem{'1', k, j})
+			}
+			if k > i && z.wm.at(i, k-1) < inf {
+				fn(0, nil, traceItem{'M', i, k - 1}, traceItem{'1', k, j})
+			}
+		}
+	}
+}
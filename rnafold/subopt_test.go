@@ -0,0 +1,76 @@
+package rnafold
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestSuboptimalExhaustive compares both enumerations against every
+// structure of short sequences.
+func TestSuboptimalExhaustive(t *testing.T) {
+	r := rand.New(rand.NewSource(16))
+	for trial := 0; trial < 15; trial++ {
+		seq := randomRNA(r, 9+r.Intn(6))
+
+		best := mustScore(t, FoldScore, seq)
+		want := map[string]bool{}
+		for _, s := range allStructures(seq, 1) {
+			if len(s.Pairs) >= best-2 {
+				want[s.DotBracket()] = true
+			}
+		}
+		s, err := Suboptimal(seq, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, last := map[string]bool{}, best
+		for s.Next() {
+			db := s.Structure().DotBracket()
+			if got[db] || !want[db] || s.Score() != len(s.Structure().Pairs) || s.Score() > last {
+				t.Fatalf("%v: unexpected %v (score %v after %v)", seq, db, s.Score(), last)
+			}
+			got[db], last = true, s.Score()
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%v: %v structures, exhaustive %v", seq, len(got), len(want))
+		}
+
+		mfe, _, _ := MFE(seq)
+		want = map[string]bool{}
+		for _, s := range allStructures(seq, minHairpin) {
+			if e, _ := EvalEnergy(seq, s); e <= mfe+1.5+1e-9 {
+				want[s.DotBracket()] = true
+			}
+		}
+		s, err = SuboptimalMFE(seq, 1.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, lastE := map[string]bool{}, mfe
+		for s.Next() {
+			db := s.Structure().DotBracket()
+			e, _ := EvalEnergy(seq, s.Structure())
+			if got[db] || !want[db] || math.Abs(e-s.Energy()) > 1e-9 || s.Energy() < lastE-1e-9 {
+				t.Fatalf("%v: unexpected %v (energy %v after %v)", seq, db, s.Energy(), lastE)
+			}
+			got[db], lastE = true, s.Energy()
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%v: %v structures, exhaustive %v", seq, len(got), len(want))
+		}
+	}
+}
+
+func TestSuboptimalEarlyStop(t *testing.T) {
+	s, err := SuboptimalMFE(bCYRN1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfe, _, _ := MFE(bCYRN1)
+	for k := 0; k < 10 && s.Next(); k++ {
+		if k == 0 && math.Abs(s.Energy()-mfe) > 1e-9 {
+			t.Fatalf("first structure %v, MFE %v", s.Energy(), mfe)
+		}
+	}
+}
@@ -1,29 +1,156 @@
 package rnafold
 
-import bio "github.com/bsjcho/bioinf"
+import (
+	"math"
+
+	bio "github.com/bsjcho/bioinf"
+)
+
+// RNA secondary structure using the Nussinov algorithm with the
+// two vector Four-Russians speedup (Frid & Gusfield).
+//
+// The recurrence used is the split form of FoldScore's:
+//   S(i,j) = max( S(i+1,j-1) + match(i,j),
+//                 max_{i<k<=j} S(i,k-1) + S(k,j) )
+// Adjacent Nussinov cells never differ by more than one, so a run of q
+// consecutive cells in a row (or column) is fully described by its first
+// value plus q-1 difference bits. The split max over a group of q values of
+// k then reduces to a single lookup into a table R indexed by the row and
+// column difference vectors, giving O(n^3/log n) time instead of O(n^3).
 
 type tvfrNussinov struct {
 	n        int
 	sequence string
 	matrix   [][]int
 	score    int
-	qSq      int
-	q        int
-	R        []int
+	qSq      int   // number of distinct difference vectors for a group (2^(q-1))
+	q        int   // group size
+	R        []int // R[rowVec*qSq+colVec] = best offset within a group
+	rowVecs  [][]int
+	colVecs  []int
 }
 
 // TVFRFoldScore returns the fold score using nussinov
 // with the two vector four russians speedup.
-func TVFRFoldScore(seq string) int {
+func TVFRFoldScore(seq string) (int, error) {
+	rna, err := Normalize(seq)
+	if err != nil {
+		return 0, err
+	}
 	var tvfr tvfrNussinov
-	tvfr.initialize(seq)
-	// TODO
-	return 0
+	tvfr.initialize(rna)
+	tvfr.fill()
+	return tvfr.score, nil
 }
 
 func (t *tvfrNussinov) initialize(seq string) {
 	t.n = len(seq)
 	t.sequence = seq
 	t.matrix = bio.Slice2D(t.n, t.n, 0)
-	// groupSize := math.Log(x) log10(x)/log10(5)
+	// group size of roughly log4(n) keeps the R table at O(n) entries
+	t.q = 1
+	if t.n > 1 {
+		t.q = bio.Max(1, int(math.Log(float64(t.n))/math.Log(4)))
+	}
+	t.qSq = 1 << uint(t.q-1)
+	t.buildR()
+	groups := t.n/t.q + 1
+	t.rowVecs = bio.Slice2D(t.n, groups, 0)
+	t.colVecs = make([]int, groups)
+}
+
+// buildR precomputes, for every pair of row and column difference vectors,
+// the best value of prefix(row, t) - prefix(col, t) over offsets t in a group.
+func (t *tvfrNussinov) buildR() {
+	t.R = make([]int, t.qSq*t.qSq)
+	for rv := 0; rv < t.qSq; rv++ {
+		for cv := 0; cv < t.qSq; cv++ {
+			best, cur := 0, 0
+			for b := 0; b < t.q-1; b++ {
+				cur += (rv>>uint(b))&1 - (cv>>uint(b))&1
+				best = bio.Max(best, cur)
+			}
+			t.R[rv*t.qSq+cv] = best
+		}
+	}
+}
+
+// fill computes the matrix column by column, bottom to top.
+func (t *tvfrNussinov) fill() {
+	if t.n == 0 {
+		return
+	}
+	for j := 1; j < t.n; j++ {
+		for i := j - 1; i >= 0; i-- {
+			t.matrix[i][j] = t.cell(i, j)
+			if i%t.q == 0 {
+				t.encodeColumn(i/t.q, j)
+			}
+			t.encodeRow(i, j)
+		}
+	}
+	t.score = t.matrix[0][t.n-1]
+}
+
+func (t *tvfrNussinov) cell(i, j int) int {
+	best := 0
+	if j-i >= 2 {
+		best = t.matrix[i+1][j-1] + t.matchScore(i, j)
+	}
+	// groups fully inside [i+1, j] are handled by lookup,
+	// the ragged ends directly.
+	first := (i + t.q) / t.q // first group starting after i
+	last := (j+1)/t.q - 1    // last group ending at or before j
+	if first > last {
+		for k := i + 1; k <= j; k++ {
+			best = bio.Max(best, t.matrix[i][k-1]+t.matrix[k][j])
+		}
+		return best
+	}
+	for k := i + 1; k < first*t.q; k++ {
+		best = bio.Max(best, t.matrix[i][k-1]+t.matrix[k][j])
+	}
+	for g := first; g <= last; g++ {
+		k := g * t.q
+		v := t.matrix[i][k-1] + t.matrix[k][j] + t.R[t.rowVecs[i][g]*t.qSq+t.colVecs[g]]
+		best = bio.Max(best, v)
+	}
+	for k := (last + 1) * t.q; k <= j; k++ {
+		best = bio.Max(best, t.matrix[i][k-1]+t.matrix[k][j])
+	}
+	return best
+}
+
+// encodeColumn stores the difference vector of column j over rows of group g.
+// Values decrease (by zero or one) moving down a column.
+func (t *tvfrNussinov) encodeColumn(g, j int) {
+	k := g * t.q
+	v := 0
+	for b := 0; b < t.q-1 && k+b+1 <= j; b++ {
+		v |= (t.matrix[k+b][j] - t.matrix[k+b+1][j]) << uint(b)
+	}
+	t.colVecs[g] = v
+}
+
+// encodeRow stores the difference vector of row i over the columns
+// g*q-1 .. g*q+q-2 once the last of them (j) has been computed.
+// Values increase (by zero or one) moving right along a row.
+func (t *tvfrNussinov) encodeRow(i, j int) {
+	if (j+2)%t.q != 0 {
+		return
+	}
+	g := (j+2)/t.q - 1
+	k := g * t.q
+	if k-1 < i {
+		return
+	}
+	v := 0
+	for b := 0; b < t.q-1; b++ {
+		v |= (t.matrix[i][k+b] - t.matrix[i][k+b-1]) << uint(b)
+	}
+	t.rowVecs[i][g] = v
+}
+
+func (t *tvfrNussinov) matchScore(i, j int) int {
+	return pairScore(t.sequence[i], t.sequence[j])
 }
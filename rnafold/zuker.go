@@ -0,0 +1,396 @@
+package rnafold
+
+import bio "github.com/bsjcho/bioinf"
+
+// Minimum free energy RNA secondary structure using the Zuker algorithm.
+// Input:
+// - A sequence composed of nucleotide bases (A,C,G,U)
+// Output:
+// - The minimum free energy in kcal/mol
+// - The secondary structure realizing it
+//
+// Tables (energies in dcal/mol):
+//   V(i,j)   loop closed by the pair (i,j)
+//   WM(i,j)  part of a multiloop within [i,j] with at least one branch
+//   WM1(i,j) part of a multiloop within [i,j] with exactly one branch,
+//            starting at i
+//   W5(j)    exterior loop over the prefix [0,j]
+// When two strands are cofolded, the innermost pair joining them closes an
+// exterior loop holding the linker, made of the 3' end of the first strand
+// (W3A) and the 5' end of the second (W5B).
+// The decomposition is unambiguous: every structure is derived exactly once.
+// This is what the partition function and suboptimal enumeration rely on.
+
+type zuker struct {
+	*energyModel
+	n            int
+	v, wm, wm1   *band
+	w5           []int // w5[j+1] is W5(j), w5[0] is the empty prefix
+	w3a          []int // w3a[i] covers [i,linkStart)
+	w5b          []int // w5b[j] covers [linkEnd,j]
+	tracePairs   []Pair
+	tracePending []traceItem
+}
+
+// traceItem is a table entry still to be traced back.
+type traceItem struct {
+	table byte // 'W', 'V', 'M', '1', 'A' (W3A) or 'B' (W5B)
+	i, j  int
+}
+
+// MFE folds seq with the nearest-neighbor energy model, returning the minimum
+// free energy in kcal/mol and a structure with that energy.
+func MFE(seq string, opts ...Options) (float64, *Structure, error) {
+	m, err := newEnergyModel(seq, opts)
+	if err != nil {
+		return 0, nil, err
+	}
+	z := newZuker(m)
+	z.fill()
+	if z.w5[z.n] >= inf {
+		return 0, nil, errInfeasible
+	}
+	pairs := z.traceback()
+	return float64(z.w5[z.n]) / 100, NewStructure(z.n, pairs), nil
+}
+
+func newZuker(m *energyModel) *zuker {
+	return newBandedZuker(m, len(m.seq))
+}
+
+// newBandedZuker only allows pairs spanning at most span bases, keeping
+// O(n·span) table entries.
+func newBandedZuker(m *energyModel, span int) *zuker {
+	n := len(m.seq)
+	return &zuker{
+		energyModel: m,
+		n:           n,
+		v:           newBand(n, span, inf),
+		wm:          newBand(n, span, inf),
+		wm1:         newBand(n, span, inf),
+		w5:          make([]int, n+1),
+	}
+}
+
+func (z *zuker) fill() {
+	z.fillCells(false)
+	if z.linkEnd > 0 {
+		z.fillStrandEnds()
+		z.fillCells(true)
+	}
+	for j := 0; j < z.n; j++ {
+		z.w5[j+1] = inf
+		if z.cons.canSkip(j, j) {
+			z.w5[j+1] = z.w5[j]
+		}
+		for i := bio.Max(0, j-z.v.width); i < j-z.minLoop; i++ {
+			if z.v.at(i, j) < inf && z.w5[i] < inf {
+				z.w5[j+1] = bio.Min(z.w5[j+1], z.w5[i]+z.v.at(i, j)+z.exterior(i, j))
+			}
+		}
+	}
+}
+
+// fillCells fills the cells reaching the linker, or those that do not.
+// Cells within one strand never depend on the others.
+func (z *zuker) fillCells(linked bool) {
+	for i := z.n - 1; i >= 0; i-- {
+		z.fillRow(i, linked)
+	}
+}
+
+// fillRow fills the cells (i, j) left to right. Every cell depends only on
+// cells to its left or in the rows below.
+func (z *zuker) fillRow(i int, linked bool) {
+	for j := i + z.minLoop + 1; j < z.n && j-i <= z.v.width; j++ {
+		if z.reachesLinker(i, j) != linked {
+			continue
+		}
+		z.v.set(i, j, z.fillV(i, j))
+		z.wm1.set(i, j, z.fillWM1(i, j))
+		z.wm.set(i, j, z.fillWM(i, j))
+	}
+}
+
+func (m *energyModel) reachesLinker(i, j int) bool {
+	return m.linkEnd > 0 && i < m.linkEnd && j >= m.linkStart
+}
+
+// fillStrandEnds computes W3A and W5B from the cells within each strand.
+func (z *zuker) fillStrandEnds() {
+	z.w3a = make([]int, z.linkStart+1)
+	for i := z.linkStart - 1; i >= 0; i-- {
+		z.w3a[i] = inf
+		if z.cons.canSkip(i, i) {
+			z.w3a[i] = z.w3a[i+1]
+		}
+		for j := i + z.minLoop + 1; j < z.linkStart; j++ {
+			if z.v.at(i, j) < inf && z.w3a[j+1] < inf {
+				z.w3a[i] = bio.Min(z.w3a[i], z.v.at(i, j)+z.exterior(i, j)+z.w3a[j+1])
+			}
+		}
+	}
+	z.w5b = make([]int, z.n)
+	for j := z.linkEnd; j < z.n; j++ {
+		z.w5b[j] = inf
+		if z.cons.canSkip(j, j) {
+			z.w5b[j] = z.w5b[j-1]
+		}
+		for i := z.linkEnd; i < j-z.minLoop; i++ {
+			if z.v.at(i, j) < inf && z.w5b[i-1] < inf {
+				z.w5b[j] = bio.Min(z.w5b[j], z.w5b[i-1]+z.v.at(i, j)+z.exterior(i, j))
+			}
+		}
+	}
+}
+
+// joinStrands returns the energy of (i, j) as the innermost pair joining
+// the two strands, or inf if it cannot be.
+func (z *zuker) joinStrands(i, j int) int {
+	if z.linkEnd == 0 || i >= z.linkStart || j < z.linkEnd ||
+		z.w3a[i+1] >= inf || z.w5b[j-1] >= inf {
+		return inf
+	}
+	return z.exterior(i, j) + z.p.duplexInit + z.w3a[i+1] + z.w5b[j-1]
+}
+
+func (z *zuker) fillV(i, j int) int {
+	if !z.canPair(i, j) {
+		return inf
+	}
+	best := z.hairpin(i, j)
+	z.interiorLoops(i, j, func(k, l int) {
+		if e := z.interior(i, j, k, l); e < inf {
+			best = bio.Min(best, e+z.v.at(k, l))
+		}
+	})
+	for k := i + 2; k < j; k++ {
+		if z.wm.at(i+1, k-1) < inf && z.wm1.at(k, j-1) < inf {
+			best = bio.Min(best, z.multiClosing(i, j)+z.wm.at(i+1, k-1)+z.wm1.at(k, j-1))
+		}
+	}
+	return bio.Min(best, z.joinStrands(i, j))
+}
+
+// interiorLoops calls fn for every inner pair (k, l) that can close
+// a stack, bulge or interior loop with (i, j).
+func (z *zuker) interiorLoops(i, j int, fn func(k, l int)) {
+	for k := i + 1; k <= i+maxLoop+1 && k < j; k++ {
+		u1 := k - i - 1
+		for l := j - 1; l > k && u1+j-l-1 <= maxLoop; l-- {
+			if z.v.at(k, l) < inf {
+				fn(k, l)
+			}
+		}
+	}
+}
+
+func (z *zuker) fillWM1(i, j int) int {
+	best := inf
+	for l := i + z.minLoop + 1; l <= j; l++ {
+		if gap := z.mlGap(l+1, j-l); z.v.at(i, l) < inf && gap < inf {
+			best = bio.Min(best, z.v.at(i, l)+z.branch(i, l)+gap)
+		}
+	}
+	return best
+}
+
+func (z *zuker) fillWM(i, j int) int {
+	best := inf
+	for k := i; k <= j; k++ {
+		if z.wm1.at(k, j) >= inf {
+			continue
+		}
+		if gap := z.mlGap(i, k-i); gap < inf {
+			best = bio.Min(best, gap+z.wm1.at(k, j))
+		}
+		if k > i && z.wm.at(i, k-1) < inf {
+			best = bio.Min(best, z.wm.at(i, k-1)+z.wm1.at(k, j))
+		}
+	}
+	return best
+}
+
+// multiClosing returns the energy of closing a multiloop with (i, j).
+func (m *energyModel) multiClosing(i, j int) int {
+	return m.p.mlClosing + m.p.mlBranch + m.terminal(i, j)
+}
+
+// branch returns the energy of (i, j) as a branch of a multiloop.
+func (m *energyModel) branch(i, j int) int {
+	return m.p.mlBranch + m.terminal(i, j)
+}
+
+// exterior returns the energy of (i, j) as a pair of the exterior loop.
+func (m *energyModel) exterior(i, j int) int {
+	return m.terminal(i, j)
+}
+
+func (z *zuker) traceback() []Pair {
+	return z.trace(traceItem{'W', 0, z.n - 1})
+}
+
+// trace returns the pairs found tracing back from the given table entries.
+func (z *zuker) trace(items ...traceItem) []Pair {
+	z.tracePairs = nil
+	z.tracePending = append([]traceItem(nil), items...)
+	for len(z.tracePending) > 0 {
+		t := z.tracePending[len(z.tracePending)-1]
+		z.tracePending = z.tracePending[:len(z.tracePending)-1]
+		switch t.table {
+		case 'W':
+			z.traceW(t.j)
+		case 'V':
+			z.traceV(t.i, t.j)
+		case 'M':
+			z.traceWM(t.i, t.j)
+		case '1':
+			z.traceWM1(t.i, t.j)
+		case 'A':
+			z.traceW3A(t.i)
+		case 'B':
+			z.traceW5B(t.j)
+		}
+	}
+	return z.tracePairs
+}
+
+func (z *zuker) push(table byte, i, j int) {
+	z.tracePending = append(z.tracePending, traceItem{table, i, j})
+}
+
+func (z *zuker) traceW(j int) {
+	if j < 0 {
+		return
+	}
+	if z.cons.canSkip(j, j) && z.w5[j+1] == z.w5[j] {
+		z.push('W', 0, j-1)
+		return
+	}
+	for i := bio.Max(0, j-z.v.width); i < j-z.minLoop; i++ {
+		if z.v.at(i, j) < inf && z.w5[i] < inf && z.w5[j+1] == z.w5[i]+z.v.at(i, j)+z.exterior(i, j) {
+			z.push('W', 0, i-1)
+			z.push('V', i, j)
+			return
+		}
+	}
+}
+
+func (z *zuker) traceV(i, j int) {
+	z.tracePairs = append(z.tracePairs, Pair{I: i, J: j})
+	e := z.v.at(i, j)
+	if e == z.hairpin(i, j) {
+		return
+	}
+	found := false
+	z.interiorLoops(i, j, func(k, l int) {
+		if found {
+			return
+		}
+		if loop := z.interior(i, j, k, l); loop < inf && e == loop+z.v.at(k, l) {
+			z.push('V', k, l)
+			found = true
+		}
+	})
+	if found {
+		return
+	}
+	for k := i + 2; k < j; k++ {
+		if z.wm.at(i+1, k-1) < inf && z.wm1.at(k, j-1) < inf &&
+			e == z.multiClosing(i, j)+z.wm.at(i+1, k-1)+z.wm1.at(k, j-1) {
+			z.push('M', i+1, k-1)
+			z.push('1', k, j-1)
+			return
+		}
+	}
+	if e == z.joinStrands(i, j) {
+		z.push('A', i+1, 0)
+		z.push('B', 0, j-1)
+	}
+}
+
+func (z *zuker) traceW3A(i int) {
+	if i >= z.linkStart {
+		return
+	}
+	if z.cons.canSkip(i, i) && z.w3a[i] == z.w3a[i+1] {
+		z.push('A', i+1, 0)
+		return
+	}
+	for j := i + z.minLoop + 1; j < z.linkStart; j++ {
+		if z.v.at(i, j) < inf && z.w3a[j+1] < inf &&
+			z.w3a[i] == z.v.at(i, j)+z.exterior(i, j)+z.w3a[j+1] {
+			z.push('V', i, j)
+			z.push('A', j+1, 0)
+			return
+		}
+	}
+}
+
+func (z *zuker) traceW5B(j int) {
+	if j < z.linkEnd {
+		return
+	}
+	if z.cons.canSkip(j, j) && z.w5b[j] == z.w5b[j-1] {
+		z.push('B', 0, j-1)
+		return
+	}
+	for i := z.linkEnd; i < j-z.minLoop; i++ {
+		if z.v.at(i, j) < inf && z.w5b[i-1] < inf &&
+			z.w5b[j] == z.w5b[i-1]+z.v.at(i, j)+z.exterior(i, j) {
+			z.push('B', 0, i-1)
+			z.push('V', i, j)
+			return
+		}
+	}
+}
+
+func (z *zuker) traceWM1(i, j int) {
+	for l := i + z.minLoop + 1; l <= j; l++ {
+		gap := z.mlGap(l+1, j-l)
+		if z.v.at(i, l) < inf && gap < inf && z.wm1.at(i, j) == z.v.at(i, l)+z.branch(i, l)+gap {
+			z.push('V', i, l)
+			return
+		}
+	}
+}
+
+func (z *zuker) traceWM(i, j int) {
+	for k := i; k <= j; k++ {
+		if z.wm1.at(k, j) >= inf {
+			continue
+		}
+		if gap := z.mlGap(i, k-i); gap < inf && z.wm.at(i, j) == gap+z.wm1.at(k, j) {
+			z.push('1', k, j)
+			return
+		}
+		if k > i && z.wm.at(i, k-1) < inf && z.wm.at(i, j) == z.wm.at(i, k-1)+z.wm1.at(k, j) {
+			z.push('M', i, k-1)
+			z.push('1', k, j)
+			return
+		}
+	}
+}
+
+// band is an upper triangular table holding the cells (i, j) with
+// j-i <= width. Cells outside it read as def.
+type band struct {
+	width int
+	def   int
+	cells [][]int // cells[i][j-i]
+}
+
+func newBand(n, width, def int) *band {
+	return &band{width: width, def: def, cells: bio.Slice2D(n, width+1, def)}
+}
+
+func (b *band) at(i, j int) int {
+	if d := j - i; d >= 0 && d <= b.width {
+		return b.cells[i][d]
+	}
+	return b.def
+}
+
+func (b *band) set(i, j, v int) {
+	b.cells[i][j-i] = v
+}
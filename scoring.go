@@ -1,47 +1,146 @@
 package bioinf
 
-var (
-	// values doubled to be able to use integers during calculations
-	// final result is converted to float then divided by two
-	match    = 6
-	mismatch = -4
-	gap      = -3
+import "math"
+
+// ScoringScheme scores alignments of bases and gaps. Scores are integers so
+// that dynamic programming stays exact, counted in units of 1/Scale: Float
+// converts a total back to the actual score.
+type ScoringScheme struct {
+	// Substitution scores a pair of bases, neither of them a gap.
+	Substitution func(a, b Base) int
+	// Gaps are the gap costs, linear if Open is zero.
+	Gaps GapCosts
+	// Scale is the number of units in a score of one, 1 if zero.
+	Scale int
+	// Ambiguity is how nucleotide ambiguity codes are scored.
+	Ambiguity Ambiguity
+}
+
+// Ambiguity is a way to score nucleotide ambiguity codes, such as N or R.
+type Ambiguity int
+
+const (
+	// ExpectedMatch averages the scores of the possible nucleotides, rounded
+	// to the nearest unit.
+	ExpectedMatch Ambiguity = iota
+	// BestMatch takes the best score of the possible nucleotides.
+	BestMatch
+	// LiteralMatch passes ambiguity codes to Substitution, for matrices
+	// scoring them such as NUC44.
+	LiteralMatch
 )
 
-// SPScore returns the score for sequences
+// GapCosts are affine gap penalties: a gap of k bases scores
+// Open + k*Extend.
+type GapCosts struct {
+	Open, Extend int
+}
+
+// DefaultScoring scores a match 3, a mismatch -2 and every gap base -1.5,
+// in half units.
+var DefaultScoring = ScoringScheme{
+	Substitution: MatchMismatch(6, -4),
+	Gaps:         GapCosts{Extend: -3},
+	Scale:        2,
+}
+
+// MatchMismatch returns a substitution function scoring identical bases
+// match and different ones mismatch.
+func MatchMismatch(match, mismatch int) func(a, b Base) int {
+	return func(a, b Base) int {
+		if a != b {
+			return mismatch
+		}
+		return match
+	}
+}
+
+// Float converts a score in the units of s to the actual score.
+func (s ScoringScheme) Float(score int) float64 {
+	if s.Scale == 0 {
+		return float64(score)
+	}
+	return float64(score) / float64(s.Scale)
+}
+
+// Pair returns the score of a pair of bases (or gap). A base against a gap
+// scores Gaps.Extend, opening a gap being left to the caller. Ambiguity
+// codes are scored as set by s.Ambiguity, and so is U, as the T it stands
+// for, so that RNA and DNA align alike.
+func (s ScoringScheme) Pair(b1, b2 Base) int {
+	switch {
+	case b1 == X && b2 == X:
+		return 0
+	case b1 == X || b2 == X:
+		return s.Gaps.Extend
+	case s.Ambiguity != LiteralMatch &&
+		(b1.Ambiguous() || b2.Ambiguous() || b1 == U || b2 == U):
+		return s.ambiguous(b1, b2)
+	}
+	return s.Substitution(b1, b2)
+}
+
+// PairScore returns the score of a pair of bases (or gap) under
+// DefaultScoring, in its half units.
+//
+// Deprecated: use ScoringScheme.Pair.
+func PairScore(b1, b2 Base) int {
+	return DefaultScoring.Pair(b1, b2)
+}
+
+// ambiguous scores the pairs of the nucleotides b1 and b2 stand for.
+func (s ScoringScheme) ambiguous(b1, b2 Base) int {
+	n1, n2 := b1.Nucleotides(), b2.Nucleotides()
+	if n1 == nil || n2 == nil {
+		// an amino acid against an ambiguity code
+		return s.Substitution(b1, b2)
+	}
+	sum, best := 0, math.MinInt64
+	for _, x := range n1 {
+		for _, y := range n2 {
+			score := s.Substitution(x, y)
+			sum += score
+			best = Max(best, score)
+		}
+	}
+	if s.Ambiguity == BestMatch {
+		return best
+	}
+	return int(math.Round(float64(sum) / float64(len(n1)*len(n2))))
+}
+
+// SPScore returns the sum-of-pairs score for aligned sequences. With affine
+// gap costs, gaps are quasi-natural (Altschul): within a pair of sequences,
+// a gap continues if the same sequence was gapped in the previous column.
 // TODO - handle cases where sequences are of differing lengths
-func SPScore(seqs []*Sequence) (score int) {
+func SPScore(seqs []*Sequence, s ScoringScheme) (score int) {
+	var prev []Base
 	for i := range seqs[0].Bases {
 		colBases := []Base{}
 		for j := range seqs {
 			colBases = append(colBases, seqs[j].Bases[i])
 		}
-		score += ColumnSPScore(colBases)
+		score += ColumnSPScore(colBases, prev, s)
+		prev = colBases
 	}
 	return
 }
 
 // ColumnSPScore returns the sum-of-pairs score for a column of bases
-func ColumnSPScore(bases []Base) (sum int) {
+// following the column prev, which is nil for the first column. prev only
+// matters for affine gap costs.
+func ColumnSPScore(bases, prev []Base, s ScoringScheme) (sum int) {
 	for i, bi := range bases[:len(bases)-1] {
-		for _, bj := range bases[i+1:] {
-			sum += PairScore(bi, bj)
+		for j, bj := range bases[i+1:] {
+			j += i + 1
+			sum += s.Pair(bi, bj)
+			switch {
+			case bi == X && bj != X && (prev == nil || prev[i] != X):
+				sum += s.Gaps.Open
+			case bj == X && bi != X && (prev == nil || prev[j] != X):
+				sum += s.Gaps.Open
+			}
 		}
 	}
 	return
 }
-
-// PairScore returns the score of a pair of bases (or gap)
-func PairScore(b1, b2 Base) int {
-	if b1 == X && b2 == X {
-		return 0
-	}
-	if (b1 == X && b2 != X) ||
-		(b2 == X && b1 != X) {
-		return gap
-	}
-	if b1 != b2 {
-		return mismatch
-	}
-	return match
-}
@@ -33,6 +33,10 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	bio "github.com/bsjcho/bioinf"
+	"github.com/bsjcho/bioinf/alignment"
+	"github.com/bsjcho/bioinf/fasta"
 )
 
 // Result holds comparison results between a protein and the base
@@ -109,6 +113,10 @@ func main() {
 		if i > maxIndex {
 			break
 		}
+		if err := checkEmissions(protein.seq); err != nil {
+			checkErr(fmt.Errorf("skipping %v: %v", protein.name, err))
+			continue
+		}
 		pro = protein
 		compareProtein()
 	}
@@ -118,7 +126,7 @@ func main() {
 	for i := 0; i < 3; i++ {
 		r := heap.Pop(results)
 		result, _ := r.(*Result)
-		result.print()
+		checkErr(result.print())
 	}
 }
 
@@ -142,7 +150,7 @@ func compareProtein() {
 	result.index = pro.index
 	result.proteinName = pro.name
 	heap.Push(results, result) // using heap to maintain order of results
-	result.print()
+	checkErr(result.print())
 }
 
 // reference materials use negative indices (specifically, -1) in matrices.
@@ -329,27 +337,34 @@ func convertToLnFloat(floatString string) float64 {
 ///////////////// Parse Functions
 
 func parseProteins(filename string) (proteins []*Protein) {
-	scanner, file := fetchScanner(filename)
+	file, err := os.Open(filename)
+	checkErr(err)
 	defer file.Close()
-	var protein *Protein
-	index := 0
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if strings.Contains(fields[0], ">") {
-			if index > 0 {
-				proteins = append(proteins, protein)
-			}
-			protein = &Protein{}
-			protein.index = index
-			protein.name = strings.Split(fields[0], "|")[2]
-			index++
-		} else {
-			protein.seq += strings.Replace(strings.TrimSpace(fields[0]), "U", "C", -1)
+	records, err := fasta.NewReader(file, bio.Protein).ReadAll()
+	checkErr(err)
+	for index, record := range records {
+		protein := &Protein{index: index, name: record.ID}
+		// UniProt IDs read db|accession|entry name
+		if fields := strings.Split(record.ID, "|"); len(fields) == 3 {
+			protein.name = fields[2]
 		}
+		protein.seq = record.Seq.String()
+		proteins = append(proteins, protein)
 	}
 	return
 }
 
+// checkEmissions returns an error if seq holds a residue the model has no
+// emission probabilities for, such as selenocysteine (U).
+func checkEmissions(seq string) error {
+	for i := 0; i < len(seq); i++ {
+		if _, ok := q[seq[i:i+1]]; !ok {
+			return fmt.Errorf("no emission probability for %q at position %d", seq[i], i)
+		}
+	}
+	return nil
+}
+
 func parseP(filename string) map[string]map[string]float64 {
 	rp := map[string]map[string]float64{}
 	scanner, file := fetchScanner(filename)
@@ -425,9 +440,17 @@ func (h *ResultHeap) Pop() interface{} {
 
 ////////////////// Aux Functions
 
-func (r *Result) print() {
-	fmt.Printf("Index=%v Name=%v ln Pr=%v\n%v\n%v\n", r.index,
-		r.proteinName, r.lnPrViterbi, r.proteinSeq[:60], r.baseSeq[:60])
+func (r *Result) print() error {
+	fmt.Printf("Index=%v Name=%v ln Pr=%v\n", r.index, r.proteinName, r.lnPrViterbi)
+	seqs, err := bio.ParseSequences([]string{r.proteinSeq, r.baseSeq}, bio.Protein)
+	if err != nil {
+		return err
+	}
+	a, err := alignment.New([]string{r.proteinName, "base"}, seqs)
+	if err != nil {
+		return err
+	}
+	return alignment.WritePairwise(os.Stdout, a)
 }
 
 func checkErr(err error) {
@@ -1,8 +1,12 @@
 package bioinf
 
-// Sequence represents a nucleotide base sequence
+import "fmt"
+
+// Sequence represents a sequence of nucleotide bases or amino acids
 type Sequence struct {
 	Bases []Base
+	// Alphabet holds the letters of the sequence, DNA if nil.
+	Alphabet *Alphabet
 }
 
 // NewSequence is a Sequence constructor
@@ -10,19 +14,191 @@ func NewSequence() *Sequence {
 	return &Sequence{Bases: []Base{}}
 }
 
-// Base represents a nucleotide base
+// Base represents a nucleotide base or an amino acid, by its letter. The
+// alphabet of a sequence tells which: A is adenine in DNA and alanine in a
+// protein.
 type Base int
 
-// A ... enum represents a nucleotide
+// A ... enum represents a nucleotide or an amino acid. A, C, G and T are
+// shared by both alphabets, the amino acids named by their three letter
+// codes are protein only.
 const (
-	A Base = iota
-	C
-	G
-	T
-	X // represents a gap "-"
+	A Base = iota // adenine, or alanine in proteins
+	C             // cytosine, or cysteine in proteins
+	G             // guanine, or glycine in proteins
+	T             // thymine, or threonine in proteins
+	X             // represents a gap "-"
+	U             // uracil, or selenocysteine in proteins
+	// the other amino acids and their ambiguity codes
+	Arg  // R
+	Asn  // N
+	Asp  // D
+	Gln  // Q
+	Glu  // E
+	His  // H
+	Ile  // I
+	Leu  // L
+	Lys  // K
+	Met  // M
+	Phe  // F
+	Pro  // P
+	Ser  // S
+	Trp  // W
+	Tyr  // Y
+	Val  // V
+	Asx  // B: D or N
+	Glx  // Z: E or Q
+	Xle  // J: I or L
+	Pyl  // O: pyrrolysine
+	Xaa  // X: any amino acid
+	Stop // *: a stop codon
+	// nucleotide ambiguity codes (IUPAC)
+	Purine     // R: A or G
+	Pyrimidine // Y: C or T
+	Strong     // S: C or G
+	Weak       // W: A or T
+	Keto       // K: G or T
+	Amino      // M: A or C
+	NotA       // B: C, G or T
+	NotC       // D: A, G or T
+	NotG       // H: A, C or T
+	NotT       // V: A, C or G
+	AnyBase    // N: an unknown base, unlike the gap X
 )
 
-// AsToSeqs converts strings to Sequences
+// letters of the bases, in the order of the enum
+const letters = "ACGT-URNDQEHILKMFPSWYVBZJOX*RYSWKMBDHVN"
+
+// nucleotides of the ambiguity codes
+var ambiguities = map[Base][]Base{
+	Purine:     {A, G},
+	Pyrimidine: {C, T},
+	Strong:     {C, G},
+	Weak:       {A, T},
+	Keto:       {G, T},
+	Amino:      {A, C},
+	NotA:       {C, G, T},
+	NotC:       {A, G, T},
+	NotG:       {A, C, T},
+	NotT:       {A, C, G},
+	AnyBase:    {A, C, G, T},
+}
+
+// Letter returns the character of a base, '-' for a gap.
+func (b Base) Letter() byte {
+	return letters[b]
+}
+
+// Ambiguous reports whether b is a nucleotide ambiguity code.
+func (b Base) Ambiguous() bool {
+	return b >= Purine && b <= AnyBase
+}
+
+// Nucleotides returns the DNA bases b may stand for: those of an ambiguity
+// code, b itself for A, C, G and T, and T for U. It returns nil for amino
+// acids and gaps.
+func (b Base) Nucleotides() []Base {
+	switch {
+	case b.Ambiguous():
+		return ambiguities[b]
+	case b == U:
+		return []Base{T}
+	case b >= A && b <= T:
+		return []Base{b}
+	}
+	return nil
+}
+
+// String returns the sequence as letters.
+func (s *Sequence) String() string {
+	b := make([]byte, len(s.Bases))
+	for i, base := range s.Bases {
+		b[i] = base.Letter()
+	}
+	return string(b)
+}
+
+// Alphabet is a set of letters a Sequence may hold, besides the gap.
+type Alphabet struct {
+	Name    string
+	Letters string
+	bases   [256]Base // base of every letter, -1 if not in the alphabet
+}
+
+// NewAlphabet returns the alphabet of the given bases. Their letters are
+// case insensitive.
+func NewAlphabet(name string, bases ...Base) *Alphabet {
+	a := &Alphabet{Name: name}
+	for i := range a.bases {
+		a.bases[i] = -1
+	}
+	a.bases['-'], a.bases['.'] = X, X
+	for _, b := range bases {
+		c := b.Letter()
+		a.Letters += string(c)
+		a.bases[c], a.bases[c|0x20] = b, b
+	}
+	return a
+}
+
+// The alphabets of nucleotide and protein sequences.
+var (
+	DNA = NewAlphabet("DNA", A, C, G, T)
+	RNA = NewAlphabet("RNA", A, C, G, U)
+	// IUPAC holds the nucleotides, U included, and their ambiguity codes.
+	IUPAC = NewAlphabet("IUPAC", A, C, G, T, U,
+		Purine, Pyrimidine, Strong, Weak, Keto, Amino, NotA, NotC, NotG, NotT, AnyBase)
+	// Protein holds the amino acids, their ambiguity codes, X for any amino
+	// acid and * for a stop codon.
+	Protein = NewAlphabet("Protein", A, C, Asp, Glu, Phe, G, His, Ile, Lys, Leu, Met, Asn,
+		Pro, Gln, Arg, Ser, T, Val, Trp, Tyr, U, Pyl, Asx, Glx, Xle, Xaa, Stop)
+)
+
+// Base returns the base of the letter c, an error if c is not in the
+// alphabet. '-' and '.' are gaps.
+func (a *Alphabet) Base(c byte) (Base, error) {
+	b := a.bases[c]
+	if b < 0 {
+		return X, fmt.Errorf("bioinf: %q is not a %s letter", c, a.Name)
+	}
+	return b, nil
+}
+
+// Contains reports whether b is a letter of the alphabet or a gap.
+func (a *Alphabet) Contains(b Base) bool {
+	return b >= 0 && int(b) < len(letters) && a.bases[b.Letter()] == b
+}
+
+// ParseSequence converts the letters of s to a Sequence of the alphabet a,
+// returning an error at the first letter outside it.
+func ParseSequence(s string, a *Alphabet) (*Sequence, error) {
+	seq := &Sequence{Bases: make([]Base, len(s)), Alphabet: a}
+	for i := 0; i < len(s); i++ {
+		b, err := a.Base(s[i])
+		if err != nil {
+			return nil, fmt.Errorf("%v at position %d", err, i)
+		}
+		seq.Bases[i] = b
+	}
+	return seq, nil
+}
+
+// ParseSequences converts strings to Sequences of the alphabet a.
+func ParseSequences(strs []string, a *Alphabet) ([]*Sequence, error) {
+	seqs := make([]*Sequence, len(strs))
+	for i, s := range strs {
+		seq, err := ParseSequence(s, a)
+		if err != nil {
+			return nil, fmt.Errorf("sequence %d: %v", i, err)
+		}
+		seqs[i] = seq
+	}
+	return seqs, nil
+}
+
+// AsToSeqs converts nucleotide strings to Sequences as AToSeq does.
+//
+// Deprecated: use ParseSequences, which rejects invalid letters.
 func AsToSeqs(seqStrs []string) (seqs []*Sequence) {
 	for _, seqStr := range seqStrs {
 		seqs = append(seqs, AToSeq(seqStr))
@@ -30,27 +206,25 @@ func AsToSeqs(seqStrs []string) (seqs []*Sequence) {
 	return
 }
 
-// AToSeq converts string to Sequence
+// AToSeq converts a nucleotide string to a Sequence of the IUPAC alphabet.
+// Letters outside it become gaps.
+//
+// Deprecated: use ParseSequence, which rejects invalid letters.
 func AToSeq(seq string) *Sequence {
-	s := NewSequence()
-	for _, b := range seq {
-		s.Bases = append(s.Bases, AToBase(string(b)))
+	s := &Sequence{Bases: make([]Base, len(seq)), Alphabet: IUPAC}
+	for i := 0; i < len(seq); i++ {
+		s.Bases[i], _ = IUPAC.Base(seq[i])
 	}
 	return s
 }
 
-// AToBase converts string to Base
+// AToBase converts a nucleotide letter to Base, a gap if it is not one.
+//
+// Deprecated: use Alphabet.Base, which rejects invalid letters.
 func AToBase(b string) Base {
-	switch b {
-	case "A":
-		return A
-	case "C":
-		return C
-	case "G":
-		return G
-	case "T":
-		return T
-	default:
+	if len(b) != 1 {
 		return X
 	}
+	base, _ := IUPAC.Base(b[0])
+	return base
 }
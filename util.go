@@ -23,3 +23,13 @@ func Max(ints ...int) int {
 	}
 	return max
 }
+
+func Min(ints ...int) int {
+	min := math.MaxInt64
+	for _, x := range ints {
+		if x < min {
+			min = x
+		}
+	}
+	return min
+}